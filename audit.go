@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+
+	"github.com/g-wilson/runtime/internal/ctxkey"
+)
+
+var auditKey = ctxkey.New("audit")
+
+type auditDetail struct {
+	mu     sync.Mutex
+	detail interface{}
+}
+
+// WithAudit prepares a context so that handlers can describe the change they made for the audit trail
+func WithAudit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditKey, &auditDetail{})
+}
+
+// SetAuditDetail sets a summary of what the handler changed, recorded in the audit entry for the request.
+// It does nothing if the context was not prepared with WithAudit
+func SetAuditDetail(ctx context.Context, detail interface{}) {
+	ad, ok := ctx.Value(auditKey).(*auditDetail)
+	if !ok {
+		return
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	ad.detail = detail
+}
+
+// AuditDetail returns the summary set by the handler with SetAuditDetail
+func AuditDetail(ctx context.Context) interface{} {
+	ad, ok := ctx.Value(auditKey).(*auditDetail)
+	if !ok {
+		return nil
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	return ad.detail
+}
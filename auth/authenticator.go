@@ -4,13 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"reflect"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/g-wilson/runtime"
 	"github.com/g-wilson/runtime/hand"
 
+	"github.com/sirupsen/logrus"
 	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 )
@@ -24,25 +29,106 @@ type OpenIDConfig struct {
 // Authenticator type is used to validate JWT access tokens and convert them into Bearer
 // types which can be used by runtime to evaluate authentication state
 type Authenticator struct {
-	Keys   *jose.JSONWebKeySet
-	Issuer string
+	Keys           *jose.JSONWebKeySet
+	Issuer         string
+	Issuers        []string
+	RequiredClaims []string
+	CustomClaims   map[string]reflect.Type
+	issuerKeys     map[string]*jose.JSONWebKeySet
+	cache          *tokenCache
+	keysMu         sync.RWMutex
+}
+
+// WithIssuer accepts tokens from an additional issuer, which are only verified with that issuer's keys.
+// Once an issuer is added, tokens from issuers which were not added are rejected before any key is used.
+func (a *Authenticator) WithIssuer(issuer string, keys *jose.JSONWebKeySet) *Authenticator {
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+
+	if a.issuerKeys == nil {
+		a.issuerKeys = map[string]*jose.JSONWebKeySet{}
+	}
+	a.issuerKeys[normalizeIssuer(issuer)] = keys
+	a.Issuers = append(a.Issuers, issuer)
+	return a
+}
+
+// WithRequiredClaims rejects tokens which do not carry all of the named claims
+func (a *Authenticator) WithRequiredClaims(names ...string) *Authenticator {
+	a.RequiredClaims = append(a.RequiredClaims, names...)
+	return a
+}
+
+// WithCustomClaim decodes the named claim into a new value of the same type as target, which must be a pointer.
+// When authenticating into *Claims the decoded pointer is available from Claims.Custom. When authenticating into a
+// *map[string]interface{}, as the execution environments do, the claim's value is replaced by the decoded pointer.
+// Tokens whose claim cannot be decoded are rejected.
+func (a *Authenticator) WithCustomClaim(name string, target interface{}) *Authenticator {
+	t := reflect.TypeOf(target)
+	if t == nil || t.Kind() != reflect.Ptr {
+		panic(fmt.Errorf("auth: custom claim %s target must be a pointer", name))
+	}
+
+	if a.CustomClaims == nil {
+		a.CustomClaims = map[string]reflect.Type{}
+	}
+	a.CustomClaims[name] = t.Elem()
+	return a
+}
+
+// WithCache enables an LRU cache of verified tokens so repeated requests skip signature verification.
+// Entries are held until the token expires, or for at most maxTTL. A size of zero or less disables the cache.
+func (a *Authenticator) WithCache(size int, maxTTL time.Duration) *Authenticator {
+	if size <= 0 {
+		a.cache = nil
+		return a
+	}
+
+	a.cache = newTokenCache(size, maxTTL)
+	return a
 }
 
 // Authenticate validates the provided JWT access token and scans the claims
 func (a *Authenticator) Authenticate(ctx context.Context, token string, dest interface{}) error {
-	tok, err := jwt.ParseSigned(token)
-	if err != nil {
-		return hand.New(runtime.ErrCodeInvalidToken).WithMessage("jwt parse error")
+	var tok *jwt.JSONWebToken
+	var cl jwt.Claims
+	var cached bool
+
+	if a.cache != nil {
+		tok, cl, cached = a.cache.get(token)
 	}
 
-	cl := jwt.Claims{}
-	if err := tok.Claims(a.Keys, &cl); err != nil {
-		return err
+	if !cached {
+		var err error
+
+		tok, err = jwt.ParseSigned(token)
+		if err != nil {
+			return hand.New(runtime.ErrCodeInvalidToken).WithMessage("jwt parse error")
+		}
+
+		// the issuer selects the keys to verify with, so it is read before the signature has been checked
+		var unverified jwt.Claims
+		if err := tok.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+			return hand.New(runtime.ErrCodeInvalidToken).WithMessage("jwt claims error")
+		}
+		if !a.acceptsIssuer(unverified.Issuer) {
+			return hand.New(runtime.ErrCodeInvalidToken).WithMessage("invalid issuer")
+		}
+
+		if err := tok.Claims(a.keysFor(unverified.Issuer), &cl); err != nil {
+			return err
+		}
+	}
+
+	// the issuer is checked by acceptsIssuer instead, which tolerates a trailing slash
+	expected := jwt.Expected{
+		Time: time.Now().UTC(),
+	}
+
+	err := cl.Validate(expected)
+	if err == nil && !a.acceptsIssuer(cl.Issuer) {
+		err = jwt.ErrInvalidIssuer
 	}
-	err = cl.Validate(jwt.Expected{
-		Issuer: a.Issuer,
-		Time:   time.Now().UTC(),
-	})
 	if err != nil {
 		var msg string
 
@@ -58,7 +144,7 @@ func (a *Authenticator) Authenticate(ctx context.Context, token string, dest int
 		case err == jwt.ErrInvalidID:
 			msg = "invalid id"
 		case err == jwt.ErrNotValidYet:
-			msg = "not valid yet"
+			msg = "token not yet valid"
 		case err == jwt.ErrExpired:
 			msg = "expired"
 		case err == jwt.ErrIssuedInTheFuture:
@@ -72,20 +158,177 @@ func (a *Authenticator) Authenticate(ctx context.Context, token string, dest int
 		return hand.New(runtime.ErrCodeInvalidToken).WithMessage(msg)
 	}
 
-	return tok.UnsafeClaimsWithoutVerification(dest)
+	if len(a.RequiredClaims) > 0 {
+		var all map[string]interface{}
+		if err := tok.UnsafeClaimsWithoutVerification(&all); err != nil {
+			return hand.New(runtime.ErrCodeInvalidToken).WithMessage("jwt claims error")
+		}
+
+		for _, name := range a.RequiredClaims {
+			if _, ok := all[name]; !ok {
+				return hand.New(runtime.ErrCodeInvalidToken).WithMessagef("missing required claim %s", name)
+			}
+		}
+	}
+
+	custom, err := a.decodeCustomClaims(tok)
+	if err != nil {
+		return err
+	}
+
+	if a.cache != nil && !cached {
+		a.cache.add(token, tok, cl)
+	}
+
+	if err := tok.UnsafeClaimsWithoutVerification(dest); err != nil {
+		return err
+	}
+
+	if len(custom) > 0 {
+		switch d := dest.(type) {
+		case *Claims:
+			d.Custom = custom
+		case *map[string]interface{}:
+			// identity providers receive the decoded values, which ClaimsFromMap keeps in Claims.Custom
+			for name, val := range custom {
+				(*d)[name] = val
+			}
+		}
+	}
+
+	return nil
+}
+
+// decodeCustomClaims decodes the configured custom claims which are present in the token
+func (a *Authenticator) decodeCustomClaims(tok *jwt.JSONWebToken) (map[string]interface{}, error) {
+	if len(a.CustomClaims) == 0 {
+		return nil, nil
+	}
+
+	var all map[string]json.RawMessage
+	if err := tok.UnsafeClaimsWithoutVerification(&all); err != nil {
+		return nil, hand.New(runtime.ErrCodeInvalidToken).WithMessage("jwt claims error")
+	}
+
+	custom := map[string]interface{}{}
+	for name, t := range a.CustomClaims {
+		raw, ok := all[name]
+		if !ok {
+			continue
+		}
+
+		v := reflect.New(t)
+		if err := json.Unmarshal(raw, v.Interface()); err != nil {
+			return nil, hand.New(runtime.ErrCodeInvalidToken).WithMessagef("invalid claim %s", name)
+		}
+		custom[name] = v.Interface()
+	}
+
+	return custom, nil
+}
+
+// acceptsIssuer compares issuers ignoring a trailing slash, which providers are inconsistent about including.
+// Any issuer is accepted when none is configured.
+func (a *Authenticator) acceptsIssuer(iss string) bool {
+	if a.Issuer == "" && len(a.Issuers) == 0 {
+		return true
+	}
+
+	iss = normalizeIssuer(iss)
+	if a.Issuer != "" && iss == normalizeIssuer(a.Issuer) {
+		return true
+	}
+	for _, accepted := range a.Issuers {
+		if iss == normalizeIssuer(accepted) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeIssuer(iss string) string {
+	return strings.TrimRight(iss, "/")
+}
+
+// keysFor returns the keys which verify tokens from the issuer. When issuers have their own keys, no other keys are
+// ever used, so one issuer cannot sign tokens which claim to be from another. Tokens from the primary Issuer are
+// verified with Keys.
+func (a *Authenticator) keysFor(iss string) *jose.JSONWebKeySet {
+	a.keysMu.RLock()
+	defer a.keysMu.RUnlock()
+
+	if len(a.issuerKeys) > 0 {
+		if keys, ok := a.issuerKeys[normalizeIssuer(iss)]; ok {
+			return keys
+		}
+		if a.Issuer == "" || normalizeIssuer(iss) != normalizeIssuer(a.Issuer) {
+			return &jose.JSONWebKeySet{}
+		}
+	}
+
+	return a.Keys
+}
+
+// setKeys replaces the keys, and forgets cached tokens so those verified with a rotated key are checked again
+func (a *Authenticator) setKeys(keys *jose.JSONWebKeySet) {
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+
+	a.Keys = keys
+	if a.cache != nil {
+		a.cache.clear()
+	}
+}
+
+// DefaultHTTPClient is used to fetch OpenID configuration and keys when no client is provided
+var DefaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// FetchConfig controls how OpenID configuration and keys are fetched
+type FetchConfig struct {
+	Client         *http.Client
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	Timeout        time.Duration
+	Logger         *logrus.Entry
+}
+
+// DefaultFetchConfig retries failed fetches up to 4 times, backing off exponentially from 250ms, for at most 30s
+var DefaultFetchConfig = FetchConfig{
+	Client:         DefaultHTTPClient,
+	MaxAttempts:    4,
+	InitialBackoff: 250 * time.Millisecond,
+	Timeout:        30 * time.Second,
 }
 
 // New creates a JWT authenticator from an OpenID configuration URL
-func New(configURL string) (a *Authenticator, err error) {
+func New(configURL string) (*Authenticator, error) {
+	return NewFromConfig(configURL, DefaultFetchConfig)
+}
+
+// NewWithClient creates a JWT authenticator from an OpenID configuration URL, fetching it and the keys with client
+// so that timeouts, proxies and trusted CAs can be configured
+func NewWithClient(configURL string, client *http.Client) (*Authenticator, error) {
+	cfg := DefaultFetchConfig
+	cfg.Client = client
+
+	return NewFromConfig(configURL, cfg)
+}
+
+// NewFromConfig creates a JWT authenticator from an OpenID configuration URL, retrying failed fetches with exponential
+// backoff. It returns an error once the attempts or the timeout are exhausted, so a service fails fast without keys.
+func NewFromConfig(configURL string, cfg FetchConfig) (a *Authenticator, err error) {
 	var keyset jose.JSONWebKeySet
 	var config OpenIDConfig
 
-	err = getJSON(configURL, &config)
+	f := newFetcher(cfg)
+
+	err = f.getJSON(configURL, &config)
 	if err != nil {
 		return
 	}
 
-	err = getJSON(config.JwksURI, &keyset)
+	err = f.getJSON(config.JwksURI, &keyset)
 	if err != nil {
 		return
 	}
@@ -98,8 +341,59 @@ func New(configURL string) (a *Authenticator, err error) {
 	return
 }
 
-func getJSON(url string, dest interface{}) (err error) {
-	resp, err := http.Get(url)
+type fetcher struct {
+	cfg      FetchConfig
+	deadline time.Time
+}
+
+func newFetcher(cfg FetchConfig) *fetcher {
+	if cfg.Client == nil {
+		cfg.Client = DefaultHTTPClient
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.NewEntry(logrus.StandardLogger())
+	}
+
+	f := &fetcher{cfg: cfg}
+	if cfg.Timeout > 0 {
+		f.deadline = time.Now().Add(cfg.Timeout)
+	}
+
+	return f
+}
+
+// getJSON fetches and decodes a JSON document, retrying with exponential backoff
+func (f *fetcher) getJSON(url string, dest interface{}) (err error) {
+	backoff := f.cfg.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err = getJSON(f.cfg.Client, url, dest)
+		if err == nil {
+			return nil
+		}
+
+		f.cfg.Logger.WithError(err).WithFields(logrus.Fields{
+			"url":     url,
+			"attempt": attempt,
+		}).Warn("auth: fetch failed")
+
+		if attempt >= f.cfg.MaxAttempts {
+			return fmt.Errorf("auth: fetching %s failed after %d attempts: %w", url, attempt, err)
+		}
+		if !f.deadline.IsZero() && time.Now().Add(backoff).After(f.deadline) {
+			return fmt.Errorf("auth: fetching %s timed out after %d attempts: %w", url, attempt, err)
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func getJSON(client *http.Client, url string, dest interface{}) (err error) {
+	resp, err := client.Get(url)
 	if err != nil {
 		return
 	}
@@ -121,3 +415,20 @@ func getJSON(url string, dest interface{}) (err error) {
 
 	return
 }
+
+// NewMultiIssuer creates a JWT authenticator which accepts tokens from any of several OpenID configuration URLs.
+// Each token is verified only with the keys of the issuer it claims to be from.
+func NewMultiIssuer(configURLs ...string) (*Authenticator, error) {
+	a := &Authenticator{}
+
+	for _, configURL := range configURLs {
+		single, err := New(configURL)
+		if err != nil {
+			return nil, err
+		}
+
+		a.WithIssuer(single.Issuer, single.Keys)
+	}
+
+	return a, nil
+}
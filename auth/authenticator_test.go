@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+type testIssuer struct {
+	key    *rsa.PrivateKey
+	keyID  string
+	server *httptest.Server
+}
+
+func newTestIssuer(t *testing.T, keyID string) *testIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ti := &testIssuer{key: key, keyID: keyID}
+	ti.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(OpenIDConfig{Issuer: ti.issuer(), JwksURI: ti.server.URL + "/jwks"})
+		case "/jwks":
+			json.NewEncoder(w).Encode(ti.keySet())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return ti
+}
+
+func (ti *testIssuer) issuer() string {
+	return ti.server.URL
+}
+
+func (ti *testIssuer) configURL() string {
+	return ti.server.URL + "/.well-known/openid-configuration"
+}
+
+func (ti *testIssuer) keySet() *jose.JSONWebKeySet {
+	return &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &ti.key.PublicKey, KeyID: ti.keyID, Algorithm: string(jose.RS256), Use: "sig"},
+	}}
+}
+
+// sign creates a token signed with the issuer's key, claiming to be from iss
+func (ti *testIssuer) sign(t *testing.T, iss string, extra map[string]interface{}) string {
+	t.Helper()
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.RS256,
+		Key:       jose.JSONWebKey{Key: ti.key, KeyID: ti.keyID},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := jwt.Signed(signer).Claims(jwt.Claims{
+		Issuer:  iss,
+		Subject: "user_1",
+		Expiry:  jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	if extra != nil {
+		builder = builder.Claims(extra)
+	}
+
+	token, err := builder.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestMultiIssuerVerifiesWithIssuerKeys(t *testing.T) {
+	a := newTestIssuer(t, "a")
+	defer a.server.Close()
+	b := newTestIssuer(t, "b")
+	defer b.server.Close()
+
+	authn, err := NewMultiIssuer(a.configURL(), b.configURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"issuer a", a.sign(t, a.issuer(), nil), true},
+		{"issuer b", b.sign(t, b.issuer(), nil), true},
+		{"issuer a claiming b", a.sign(t, b.issuer(), nil), false},
+		{"issuer b claiming a", b.sign(t, a.issuer(), nil), false},
+		{"unconfigured issuer", a.sign(t, "https://other.example.com", nil), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims Claims
+			err := authn.Authenticate(context.Background(), tt.token, &claims)
+			if tt.valid && err != nil {
+				t.Fatalf("expected token to be accepted, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestWithIssuerKeepsPrimaryIssuer(t *testing.T) {
+	primary := newTestIssuer(t, "primary")
+	defer primary.server.Close()
+	added := newTestIssuer(t, "added")
+	defer added.server.Close()
+
+	authn, err := New(primary.configURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	authn.WithIssuer(added.issuer(), added.keySet())
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"primary issuer", primary.sign(t, primary.issuer(), nil), true},
+		{"added issuer", added.sign(t, added.issuer(), nil), true},
+		{"added issuer claiming primary", added.sign(t, primary.issuer(), nil), false},
+		{"primary issuer claiming added", primary.sign(t, added.issuer(), nil), false},
+		{"unknown issuer", primary.sign(t, "https://other.example.com", nil), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims Claims
+			err := authn.Authenticate(context.Background(), tt.token, &claims)
+			if tt.valid && err != nil {
+				t.Fatalf("expected token to be accepted, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected token to be rejected")
+			}
+		})
+	}
+}
+
+type testOrg struct {
+	ID   string `json:"id"`
+	Plan string `json:"plan"`
+}
+
+func TestCustomClaimsReachIdentityProviders(t *testing.T) {
+	iss := newTestIssuer(t, "a")
+	defer iss.server.Close()
+
+	authn := (&Authenticator{Keys: iss.keySet(), Issuer: iss.issuer()}).WithCustomClaim("org", &testOrg{})
+	token := iss.sign(t, iss.issuer(), map[string]interface{}{
+		"org": map[string]interface{}{"id": "org_1", "plan": "pro"},
+	})
+
+	// the execution environments authenticate into a map, which is passed to the identity provider
+	var raw map[string]interface{}
+	if err := authn.Authenticate(context.Background(), token, &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ClaimsFromMap(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	org, ok := claims.Custom["org"].(*testOrg)
+	if !ok {
+		t.Fatalf("expected decoded org claim, got %#v", claims.Custom["org"])
+	}
+	if org.ID != "org_1" || org.Plan != "pro" {
+		t.Fatalf("unexpected org claim %+v", org)
+	}
+	if _, ok := claims.Extra["org"]; ok {
+		t.Fatal("expected custom claim not to be duplicated in Extra")
+	}
+}
+
+func TestCustomClaimDecodeFailure(t *testing.T) {
+	iss := newTestIssuer(t, "a")
+	defer iss.server.Close()
+
+	authn := (&Authenticator{Keys: iss.keySet(), Issuer: iss.issuer()}).WithCustomClaim("org", &testOrg{})
+	token := iss.sign(t, iss.issuer(), map[string]interface{}{"org": "not an object"})
+
+	var claims Claims
+	if err := authn.Authenticate(context.Background(), token, &claims); err == nil {
+		t.Fatal("expected token with an undecodable custom claim to be rejected")
+	}
+}
+
+func TestIssuerTrailingSlash(t *testing.T) {
+	iss := newTestIssuer(t, "a")
+	defer iss.server.Close()
+
+	tests := []struct {
+		name       string
+		configured string
+		token      string
+		valid      bool
+	}{
+		{"configured with slash", "https://issuer.example.com/", "https://issuer.example.com", true},
+		{"token with slash", "https://issuer.example.com", "https://issuer.example.com/", true},
+		{"both with slash", "https://issuer.example.com/", "https://issuer.example.com/", true},
+		{"different issuer", "https://issuer.example.com/", "https://other.example.com/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authn := &Authenticator{Keys: iss.keySet(), Issuer: tt.configured}
+
+			var claims Claims
+			err := authn.Authenticate(context.Background(), iss.sign(t, tt.token, nil), &claims)
+			if tt.valid && err != nil {
+				t.Fatalf("expected token to be accepted, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestTokenValidityWindow(t *testing.T) {
+	iss := newTestIssuer(t, "a")
+	defer iss.server.Close()
+
+	authn := &Authenticator{Keys: iss.keySet(), Issuer: iss.issuer()}
+	hour := time.Hour
+
+	tests := []struct {
+		name    string
+		extra   map[string]interface{}
+		message string
+	}{
+		{"valid", nil, ""},
+		{"not yet valid", map[string]interface{}{"nbf": time.Now().Add(hour).Unix()}, "token not yet valid"},
+		{"expired", map[string]interface{}{"exp": time.Now().Add(-hour).Unix()}, "expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims Claims
+			err := authn.Authenticate(context.Background(), iss.sign(t, iss.issuer(), tt.extra), &claims)
+
+			if tt.message == "" {
+				if err != nil {
+					t.Fatalf("expected token to be accepted, got %v", err)
+				}
+				return
+			}
+
+			handErr, ok := err.(hand.E)
+			if !ok || handErr.Code != runtime.ErrCodeInvalidToken || handErr.Message != tt.message {
+				t.Fatalf("expected invalid_token with message %q, got %#v", tt.message, err)
+			}
+		})
+	}
+}
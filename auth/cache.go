@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// tokenCache is an LRU cache of tokens which have already passed signature verification
+type tokenCache struct {
+	mu     sync.Mutex
+	size   int
+	maxTTL time.Duration
+	ll     *list.List
+	items  map[string]*list.Element
+}
+
+type tokenCacheEntry struct {
+	token     string
+	parsed    *jwt.JSONWebToken
+	claims    jwt.Claims
+	expiresAt time.Time
+}
+
+func newTokenCache(size int, maxTTL time.Duration) *tokenCache {
+	return &tokenCache{
+		size:   size,
+		maxTTL: maxTTL,
+		ll:     list.New(),
+		items:  make(map[string]*list.Element),
+	}
+}
+
+func (c *tokenCache) get(token string) (*jwt.JSONWebToken, jwt.Claims, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[token]
+	if !ok {
+		return nil, jwt.Claims{}, false
+	}
+
+	entry := el.Value.(*tokenCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(el)
+		delete(c.items, token)
+		return nil, jwt.Claims{}, false
+	}
+
+	c.ll.MoveToFront(el)
+
+	return entry.parsed, entry.claims, true
+}
+
+func (c *tokenCache) add(token string, parsed *jwt.JSONWebToken, claims jwt.Claims) {
+	expiresAt := time.Now().Add(c.maxTTL)
+	if claims.Expiry != nil && claims.Expiry.Time().Before(expiresAt) {
+		expiresAt = claims.Expiry.Time()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[token]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*tokenCacheEntry).expiresAt = expiresAt
+		return
+	}
+
+	c.items[token] = c.ll.PushFront(&tokenCacheEntry{
+		token:     token,
+		parsed:    parsed,
+		claims:    claims,
+		expiresAt: expiresAt,
+	})
+
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*tokenCacheEntry).token)
+	}
+}
+
+// clear removes every entry, so tokens are verified again
+func (c *tokenCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
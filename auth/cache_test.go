@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheClearedOnKeyRotation(t *testing.T) {
+	old := newTestIssuer(t, "old")
+	defer old.server.Close()
+	rotated := newTestIssuer(t, "new")
+	defer rotated.server.Close()
+
+	authn := (&Authenticator{Keys: old.keySet(), Issuer: "https://issuer.example.com"}).WithCache(10, time.Hour)
+	token := old.sign(t, "https://issuer.example.com", nil)
+
+	var claims Claims
+	if err := authn.Authenticate(context.Background(), token, &claims); err != nil {
+		t.Fatalf("expected token to be accepted before rotation, got %v", err)
+	}
+
+	authn.setKeys(rotated.keySet())
+
+	if err := authn.Authenticate(context.Background(), token, &claims); err == nil {
+		t.Fatal("expected cached token signed with a rotated key to be rejected")
+	}
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Wildcard matches any action, resource type or resource ID in a Grant
+const Wildcard = "*"
+
+// Claims encapsulates the authentication state carried by an access token
+type Claims struct {
+	Issuer      string  `json:"iss,omitempty"`
+	Subject     string  `json:"sub"`
+	Scope       string  `json:"scope,omitempty"`
+	Permissions []Grant `json:"permissions,omitempty"`
+	// Extra holds authorizer context which is not otherwise recognised, such as tenant or plan
+	Extra map[string]string `json:"-"`
+	// Custom holds structured claims decoded by an Authenticator configured with WithCustomClaim
+	Custom map[string]interface{} `json:"-"`
+}
+
+// ClaimsFromMap converts the claims passed to an identity provider into Claims. Claims which are not recognised are
+// kept in Extra as strings, limited to the named keys when any are given. Custom claims decoded by the Authenticator
+// are kept in Custom.
+func ClaimsFromMap(raw map[string]interface{}, extraKeys ...string) (Claims, error) {
+	var claims Claims
+
+	b, err := json.Marshal(decodeStringClaims(raw))
+	if err != nil {
+		return claims, err
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return claims, err
+	}
+
+	for key, val := range raw {
+		switch key {
+		case "iss", "sub", "scope", "permissions", "aud", "exp", "iat", "nbf", "jti":
+			continue
+		}
+		if val != nil && reflect.TypeOf(val).Kind() == reflect.Ptr {
+			// custom claims decoded by an Authenticator configured with WithCustomClaim
+			if claims.Custom == nil {
+				claims.Custom = map[string]interface{}{}
+			}
+			claims.Custom[key] = val
+			continue
+		}
+		if len(extraKeys) > 0 && !contains(extraKeys, key) {
+			continue
+		}
+
+		if claims.Extra == nil {
+			claims.Extra = map[string]string{}
+		}
+		if str, ok := val.(string); ok {
+			claims.Extra[key] = str
+		} else {
+			claims.Extra[key] = fmt.Sprint(val)
+		}
+	}
+
+	return claims, nil
+}
+
+// decodeStringClaims parses structured claims which arrive JSON encoded as strings, as API Gateway's JWT authorizer
+// passes every claim as a string
+func decodeStringClaims(raw map[string]interface{}) map[string]interface{} {
+	perms, ok := raw["permissions"].(string)
+	if !ok {
+		return raw
+	}
+
+	decoded := make(map[string]interface{}, len(raw))
+	for key, val := range raw {
+		decoded[key] = val
+	}
+	decoded["permissions"] = json.RawMessage(perms)
+
+	return decoded
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Grant allows an action to be performed on a resource, or on every resource of a type when ResourceID is empty or a wildcard
+type Grant struct {
+	Action       string `json:"action"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id,omitempty"`
+}
+
+// Can returns true if any of the claims' grants permit the action on the resource
+func (c Claims) Can(action, resourceType, resourceID string) bool {
+	for _, g := range c.Permissions {
+		if g.allows(action, resourceType, resourceID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g Grant) allows(action, resourceType, resourceID string) bool {
+	return matchGrantField(g.Action, action) &&
+		matchGrantField(g.ResourceType, resourceType) &&
+		(g.ResourceID == "" || matchGrantField(g.ResourceID, resourceID))
+}
+
+func matchGrantField(granted, requested string) bool {
+	return granted == Wildcard || granted == requested
+}
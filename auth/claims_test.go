@@ -0,0 +1,58 @@
+package auth
+
+import "testing"
+
+func TestClaimsFromMapPermissions(t *testing.T) {
+	grants := []Grant{{Action: "read", ResourceType: "document", ResourceID: "doc_1"}}
+
+	tests := []struct {
+		name string
+		raw  map[string]interface{}
+	}{
+		{"structured", map[string]interface{}{
+			"sub":         "user_1",
+			"permissions": []interface{}{map[string]interface{}{"action": "read", "resource_type": "document", "resource_id": "doc_1"}},
+		}},
+		{"json string from api gateway", map[string]interface{}{
+			"sub":         "user_1",
+			"permissions": `[{"action":"read","resource_type":"document","resource_id":"doc_1"}]`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ClaimsFromMap(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(claims.Permissions) != 1 || claims.Permissions[0] != grants[0] {
+				t.Fatalf("expected permissions %v, got %v", grants, claims.Permissions)
+			}
+			if !claims.Can("read", "document", "doc_1") {
+				t.Fatal("expected grant to allow reading the document")
+			}
+		})
+	}
+}
+
+func TestClaimsFromMapInvalidPermissions(t *testing.T) {
+	_, err := ClaimsFromMap(map[string]interface{}{"sub": "user_1", "permissions": "not json"})
+	if err == nil {
+		t.Fatal("expected malformed permissions to be an error")
+	}
+}
+
+func TestClaimsFromMapExtra(t *testing.T) {
+	claims, err := ClaimsFromMap(map[string]interface{}{"sub": "user_1", "tenant": "acme", "plan": "pro"}, "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Extra["tenant"] != "acme" {
+		t.Fatalf("expected tenant extra claim, got %v", claims.Extra)
+	}
+	if _, ok := claims.Extra["plan"]; ok {
+		t.Fatal("expected claims outside the named keys to be dropped")
+	}
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/g-wilson/runtime/internal/ctxkey"
+)
+
+var identityKey = ctxkey.New("identity")
+
+// SetIdentityContext adds authenticated claims to a context, for use by identity providers
+func SetIdentityContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, identityKey, claims)
+}
+
+// GetIdentityContext retrieves a copy of the authenticated claims from the context.
+// It returns false when the request has no identity, so this can be told apart from an identity with empty claims.
+func GetIdentityContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(identityKey).(Claims)
+	if !ok {
+		return nil, false
+	}
+
+	return &claims, true
+}
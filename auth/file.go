@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+// NewFromFile creates a JWT authenticator from a JWKS file on disk, for offline local development
+func NewFromFile(path, issuer string) (*Authenticator, error) {
+	keyset, err := readKeySet(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{
+		Keys:   keyset,
+		Issuer: issuer,
+	}, nil
+}
+
+// WatchFile polls a JWKS file for modifications and reloads the authenticator's keys when it changes.
+// If the file cannot be read or parsed the previous keys are kept. Call the returned function to stop watching.
+func (a *Authenticator) WatchFile(path string, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+
+	var lastMod time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				info, err := os.Stat(path)
+				if err != nil || !info.ModTime().After(lastMod) {
+					continue
+				}
+
+				keyset, err := readKeySet(path)
+				if err != nil {
+					continue
+				}
+
+				lastMod = info.ModTime()
+				a.setKeys(keyset)
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func readKeySet(path string) (*jose.JSONWebKeySet, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: reading jwks file: %w", err)
+	}
+
+	var keyset jose.JSONWebKeySet
+	if err := json.Unmarshal(b, &keyset); err != nil {
+		return nil, fmt.Errorf("auth: parsing jwks file %s: %w", path, err)
+	}
+	if len(keyset.Keys) == 0 {
+		return nil, fmt.Errorf("auth: jwks file %s contains no keys", path)
+	}
+
+	return &keyset, nil
+}
@@ -0,0 +1,36 @@
+package runtime
+
+import (
+	"context"
+
+	"github.com/g-wilson/runtime/internal/ctxkey"
+)
+
+var configKey = ctxkey.New("config")
+
+// FeatureFlags can be implemented by a config to answer FeatureEnabled
+type FeatureFlags interface {
+	Enabled(name string) bool
+}
+
+// WithConfig adds application config to a context, so handlers read it from the request instead of globals and tests
+// can override it per request. Handlers assert the value back to their own config type:
+//
+//	cfg, _ := runtime.ConfigFromContext(ctx)
+//	appCfg := cfg.(*AppConfig)
+func WithConfig(ctx context.Context, cfg interface{}) context.Context {
+	return context.WithValue(ctx, configKey, cfg)
+}
+
+// ConfigFromContext retrieves application config from the context
+func ConfigFromContext(ctx context.Context) (interface{}, bool) {
+	cfg := ctx.Value(configKey)
+	return cfg, cfg != nil
+}
+
+// FeatureEnabled reports whether a feature is enabled by the config on the context, which is false when there is no
+// config or it does not implement FeatureFlags
+func FeatureEnabled(ctx context.Context, name string) bool {
+	flags, ok := ctx.Value(configKey).(FeatureFlags)
+	return ok && flags.Enabled(name)
+}
@@ -0,0 +1,39 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+)
+
+type testFlags map[string]bool
+
+func (f testFlags) Enabled(name string) bool {
+	return f[name]
+}
+
+func TestConfigFromContext(t *testing.T) {
+	if _, ok := ConfigFromContext(context.Background()); ok {
+		t.Fatal("expected no config on an empty context")
+	}
+
+	ctx := WithConfig(context.Background(), testFlags{"new_checkout": true})
+
+	cfg, ok := ConfigFromContext(ctx)
+	if _, isFlags := cfg.(testFlags); !ok || !isFlags {
+		t.Fatalf("expected the config back, got %#v", cfg)
+	}
+}
+
+func TestFeatureEnabled(t *testing.T) {
+	ctx := WithConfig(context.Background(), testFlags{"new_checkout": true})
+
+	if !FeatureEnabled(ctx, "new_checkout") || FeatureEnabled(ctx, "dark_mode") {
+		t.Fatal("expected flags to be read from the config")
+	}
+	if FeatureEnabled(context.Background(), "new_checkout") {
+		t.Fatal("expected flags to be disabled without config")
+	}
+	if FeatureEnabled(WithConfig(context.Background(), struct{}{}), "new_checkout") {
+		t.Fatal("expected flags to be disabled when the config has none")
+	}
+}
@@ -0,0 +1,23 @@
+package runtime
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// TimeRemaining returns how long is left before the context deadline, such as the Lambda execution timeout.
+// It returns zero once the deadline has passed, and the maximum duration if the context has no deadline.
+func TimeRemaining(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return time.Duration(math.MaxInt64)
+	}
+
+	remaining := time.Until(deadline)
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
@@ -0,0 +1,48 @@
+package devserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+func TestBinaryResponse(t *testing.T) {
+	pdf := []byte("%PDF-1.4\x00\xff")
+
+	svc := rpcservice.NewService(testLogger()).
+		AddMethod("render", func(ctx context.Context) (*rpcservice.BinaryResponse, error) {
+			return &rpcservice.BinaryResponse{ContentType: "application/pdf", Body: pdf}, nil
+		}, nil)
+
+	rec := serve(newTestServer(nil, svc), postRPC("/svc/render", "", nil))
+	assertErrorCode(t, rec, "")
+
+	if rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("expected the handler's content type, got %s", rec.Header().Get("Content-Type"))
+	}
+	if rec.Body.String() != string(pdf) {
+		t.Fatalf("expected the raw bytes, got %q", rec.Body.String())
+	}
+}
+
+func TestNilBinaryResponse(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		AddMethod("render", func(ctx context.Context) (*rpcservice.BinaryResponse, error) {
+			return nil, nil
+		}, nil).
+		SetFallback(func(ctx context.Context, methodName string, body []byte) (interface{}, error) {
+			var bin *rpcservice.BinaryResponse
+			return bin, nil
+		})
+
+	s := newTestServer(nil, svc)
+
+	for _, method := range []string{"render", "unregistered"} {
+		rec := serve(s, postRPC("/svc/"+method, "", nil))
+		if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+			t.Fatalf("%s: expected no content, got %d: %s", method, rec.Code, rec.Body.String())
+		}
+	}
+}
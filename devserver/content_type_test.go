@@ -0,0 +1,68 @@
+package devserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestAcceptedContentTypes(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		AddMethod("search", search, gojsonschema.NewStringLoader(`{"type": "object"}`))
+
+	tests := []struct {
+		contentType string
+		accepted    []string
+		status      int
+	}{
+		{"application/json", nil, http.StatusOK},
+		{"application/json; charset=utf-8", nil, http.StatusOK},
+		{"Application/JSON", nil, http.StatusOK},
+		{"text/plain", nil, http.StatusUnsupportedMediaType},
+		{"", nil, http.StatusUnsupportedMediaType},
+		{"text/plain", []string{"application/json", "text/plain"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		s := newTestServer(nil, svc)
+		if tt.accepted != nil {
+			s.AcceptedContentTypes = tt.accepted
+		}
+
+		req := postRPC("/svc/search", `{"q": "shoes"}`, map[string]string{"Content-Type": tt.contentType})
+		rec := httptest.NewRecorder()
+		s.r.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%q accepting %v: expected %d, got %d: %s", tt.contentType, tt.accepted, tt.status, rec.Code, rec.Body.String())
+		}
+		if tt.status == http.StatusUnsupportedMediaType {
+			assertErrorCode(t, rec, runtime.ErrCodeUnsupportedMediaType)
+		}
+	}
+}
+
+func TestEmptyBodyNeedsNoContentType(t *testing.T) {
+	s := newTestServer(nil, rpcservice.NewService(testLogger()).AddMethod("ping", ping, nil))
+
+	assertErrorCode(t, serve(s, postRPC("/svc/ping", "", nil)), "")
+}
+
+func TestUnsupportedMediaTypeError(t *testing.T) {
+	s := newTestServer(nil, rpcservice.NewService(testLogger()).AddMethod("ping", ping, nil))
+
+	rec := serve(s, postRPC("/svc/ping", "<ping/>", map[string]string{"Content-Type": "application/xml"}))
+
+	assertErrorCode(t, rec, runtime.ErrCodeUnsupportedMediaType)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Type") != "application/json; charset=utf-8" || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("expected a JSON error with CORS headers, got %v", rec.Header())
+	}
+}
@@ -0,0 +1,59 @@
+package devserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name   string
+		maxAge time.Duration
+		header string
+	}{
+		{"default", 0, "600"},
+		{"configured", 30 * time.Second, "30"},
+		{"disabled", -1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(":0", testAuthenticator())
+			s.Log = testLogger()
+			if tt.maxAge != 0 {
+				s.CORSMaxAge = tt.maxAge
+			}
+			s.AddService("svc", rpcservice.NewService(testLogger()).AddMethod("ping", ping, nil))
+
+			rec := serve(s, httptest.NewRequest(http.MethodOptions, "/svc/ping", nil))
+
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("expected 204, got %d", rec.Code)
+			}
+			if maxAge := rec.Header().Get("Access-Control-Max-Age"); maxAge != tt.header {
+				t.Fatalf("expected max age %q, got %q", tt.header, maxAge)
+			}
+
+			allowed := strings.Split(rec.Header().Get("Access-Control-Allow-Headers"), ",")
+			for _, header := range []string{"Authorization", "Content-Type", "Accept-Version", "If-None-Match"} {
+				if !containsString(allowed, header) {
+					t.Errorf("expected %s to be allowed, got %v", header, allowed)
+				}
+			}
+		})
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +1,22 @@
 package devserver
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/g-wilson/runtime"
-	"github.com/g-wilson/runtime/auth"
 	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/internal/ctxkey"
 	"github.com/g-wilson/runtime/logger"
 	"github.com/g-wilson/runtime/rpcservice"
 
@@ -19,15 +26,35 @@ import (
 )
 
 // Server is our dev server instance
+// Set PrettyJSON to indent all JSON response bodies, or pass ?pretty=true on individual requests
+// AcceptedContentTypes lists the request media types accepted, parameters such as charset are ignored.
+// Add rpcservice.ContentTypeMsgpack to it to serve services which accept msgpack.
+// CORSMaxAge sets how long browsers may cache preflight responses.
+// StripTrailingSlashes rewrites request paths ending in a slash so /svc/method/ is served as /svc/method. A rewrite is
+// used rather than a redirect, because clients may not resend the body of a redirected POST.
+// CaseInsensitiveMethods resolves method names which only differ in case from a registered method.
+// QueryTokenAuth accepts the access token from an access_token query parameter when there is no Authorization header,
+// for manual testing in a browser. The parameter is removed from the request URL once read so it is never logged.
+// RedactIdentity stops the subject and scopes of authenticated requests from being added to request logs.
 type Server struct {
-	ListenAddress string
-	Log           *logrus.Entry
-	r             *chi.Mux
-	authn         *auth.Authenticator
+	ListenAddress          string
+	Log                    *logrus.Entry
+	PrettyJSON             bool
+	AcceptedContentTypes   []string
+	CORSMaxAge             time.Duration
+	StripTrailingSlashes   bool
+	CaseInsensitiveMethods bool
+	QueryTokenAuth         bool
+	RedactIdentity         bool
+	r                      *chi.Mux
+	authn                  Authenticator
+	cookieAuthn            *CookieAuthenticator
+	recorder               *recorder
+	services               []*rpcservice.Service
 }
 
 // New creates a dev server
-func New(addr string, authn *auth.Authenticator) *Server {
+func New(addr string, authn Authenticator) *Server {
 	log := logger.Create("debug", "text", "debug")
 
 	r := chi.NewRouter()
@@ -35,32 +62,83 @@ func New(addr string, authn *auth.Authenticator) *Server {
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
-	r.Use(middleware.AllowContentType("application/json"))
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		sendHTTPError(w, r, hand.New(runtime.ErrCodeMethodNotFound))
+	})
 
 	s := &Server{
-		ListenAddress: addr,
-		Log:           log,
-		r:             r,
-		authn:         authn,
+		ListenAddress:        addr,
+		Log:                  log,
+		AcceptedContentTypes: []string{"application/json", rpcservice.ContentTypeNDJSON},
+		CORSMaxAge:           600 * time.Second,
+		r:                    r,
+		authn:                authn,
 	}
 
+	r.Use(s.stripTrailingSlashes)
+
 	return s
 }
 
 // AddService maps an RPC Service's methods to HTTP path on the server's router
 func (s *Server) AddService(path string, svc *rpcservice.Service) *Server {
+	s.services = append(s.services, svc)
+
 	s.r.Route(fmt.Sprintf("/%s", path), func(r chi.Router) {
 		r.Use(attachRequestLogger(svc.Logger))
-		r.Options("/*", optionsHandler)
+		r.Use(attachService(svc))
+		r.Use(s.allowContentType)
+		r.Use(s.prettyJSON)
+		r.Options("/*", s.optionsHandler)
+		r.Get("/_schema", schemaHandler(svc))
+		r.Get("/_stats", statsHandler(svc))
 
 		for name, method := range svc.Methods {
-			r.Post("/"+name, wrapRPCMethod(svc, method, s.authn))
+			r.Post("/"+name, s.wrapRPCMethod(svc, method))
+
+			if method.HTTPVerb != "" && method.HTTPVerb != http.MethodPost {
+				r.Method(method.HTTPVerb, "/"+name, s.wrapRPCMethod(svc, method))
+			}
 		}
+
+		// method names which only match after normalisation, such as namespaced methods given as path segments,
+		// are resolved at request time
+		r.Post("/*", func(w http.ResponseWriter, r *http.Request) {
+			method, ok := s.findMethod(svc, chi.URLParam(r, "*"))
+			if !ok {
+				sendHTTPError(w, r, hand.New(runtime.ErrCodeMethodNotFound))
+				return
+			}
+
+			s.wrapRPCMethod(svc, method)(w, r)
+		})
 	})
 
 	return s
 }
 
+// Mount attaches an arbitrary HTTP handler to the server's router, for bespoke local tooling alongside RPC services.
+// Routes added this way bypass the RPC authentication, content type and request logging middleware.
+func (s *Server) Mount(path string, h http.Handler) *Server {
+	s.r.Mount(path, h)
+
+	return s
+}
+
+// WithCookieAuthenticator authenticates requests without an Authorization header using a signed session cookie
+func (s *Server) WithCookieAuthenticator(c *CookieAuthenticator) *Server {
+	s.cookieAuthn = c
+
+	return s
+}
+
+// WithRecorder writes every RPC request and its outcome to w as JSON lines, for use as test fixtures
+func (s *Server) WithRecorder(w io.Writer) *Server {
+	s.recorder = &recorder{w: w}
+
+	return s
+}
+
 // Listen starts listening for HTTP requests and blocks unless it panics
 func (s *Server) Listen() {
 	s.Log.Infof("runtime dev server listening on %q\n", s.ListenAddress)
@@ -70,10 +148,140 @@ func (s *Server) Listen() {
 	}
 }
 
+// ListenWithContext starts listening for HTTP requests until ctx is cancelled, then drains in-flight requests
+// and shuts down every service added to the server
+func (s *Server) ListenWithContext(ctx context.Context) error {
+	srv := &http.Server{Addr: s.ListenAddress, Handler: s.r}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.Log.Infof("runtime dev server listening on %q\n", s.ListenAddress)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var problems []string
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	seen := map[*rpcservice.Service]bool{}
+	for _, svc := range s.services {
+		if seen[svc] {
+			continue
+		}
+		seen[svc] = true
+
+		if err := svc.Shutdown(shutdownCtx); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("devserver shutdown failed: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// findMethod resolves a method by name, ignoring case when CaseInsensitiveMethods is set
+func (s *Server) findMethod(svc *rpcservice.Service, name string) (*rpcservice.Method, bool) {
+	if s.CaseInsensitiveMethods && !svc.HasMethod(name) {
+		for _, registered := range svc.MethodNames() {
+			if strings.EqualFold(registered, name) {
+				return svc.Methods[registered], true
+			}
+		}
+	}
+
+	return svc.GetMethod(name)
+}
+
+func (s *Server) stripTrailingSlashes(next http.Handler) http.Handler {
+	stripped := middleware.StripSlashes(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.StripTrailingSlashes {
+			stripped.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+var prettyKey = ctxkey.New("pretty")
+var serviceKey = ctxkey.New("service")
+
+// attachService makes the service available to error responses, so they can be shaped by its settings
+func attachService(svc *rpcservice.Service) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), serviceKey, svc)))
+		})
+	}
+}
+
+func (s *Server) prettyJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.PrettyJSON || r.URL.Query().Get("pretty") == "true" {
+			r = r.WithContext(context.WithValue(r.Context(), prettyKey, true))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowContentType responds with an unsupported_media_type error to request bodies not in AcceptedContentTypes
+func (s *Server) allowContentType(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err == nil {
+			for _, accepted := range s.AcceptedContentTypes {
+				if strings.EqualFold(mediaType, accepted) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		sendHTTPError(w, r, hand.New(runtime.ErrCodeUnsupportedMediaType).
+			WithMessagef("content type must be one of %s", strings.Join(s.AcceptedContentTypes, ", ")))
+	})
+}
+
+func isPretty(r *http.Request) bool {
+	pretty, _ := r.Context().Value(prettyKey).(bool)
+	return pretty
+}
+
+func encodeJSON(r *http.Request, v interface{}) ([]byte, error) {
+	if isPretty(r) {
+		return json.MarshalIndent(v, "", "  ")
+	}
+
+	return json.Marshal(v)
+}
+
 func attachRequestLogger(logInstance *logrus.Entry) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			r = r.WithContext(logger.SetContext(r.Context(), logInstance))
+			ctx := runtime.WithRequestID(r.Context(), middleware.GetReqID(r.Context()))
+			r = r.WithContext(logger.SetContext(ctx, logInstance))
 
 			reqLogger := logger.FromContext(r.Context())
 
@@ -87,10 +295,30 @@ func attachRequestLogger(logInstance *logrus.Entry) func(next http.Handler) http
 	}
 }
 
-func wrapRPCMethod(svc *rpcservice.Service, method *rpcservice.Method, authn *auth.Authenticator) http.HandlerFunc {
+func (s *Server) wrapRPCMethod(svc *rpcservice.Service, method *rpcservice.Method) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		ctx := runtime.WithResponseHeaders(r.Context())
 		reqLogger := logger.FromContext(ctx)
+		reqLogger.Update(reqLogger.Entry().WithField("method", method.Name))
+
+		method, version, err := method.ResolveVersion(r.Header.Get("Accept-Version"))
+		if err != nil {
+			sendHTTPError(w, r, err)
+			return
+		}
+		if version != "" {
+			w.Header().Set("Content-Version", version)
+			reqLogger.Update(reqLogger.Entry().WithField("method_version", version))
+		}
+
+		var queryToken string
+		if s.QueryTokenAuth {
+			queryToken = takeQueryToken(r)
+		}
+
+		if method.Deprecation != nil {
+			setDeprecationHeaders(w, method.Deprecation)
+		}
 
 		if r.Body == nil {
 			setCORSHeaders(w)
@@ -98,14 +326,65 @@ func wrapRPCMethod(svc *rpcservice.Service, method *rpcservice.Method, authn *au
 			return
 		}
 		defer r.Body.Close()
-		body, err := ioutil.ReadAll(r.Body)
+
+		ctx = runtime.WithRequestMeta(ctx, requestMeta(r, svc.RequestMetaHeaders))
+
+		var body []byte
+
+		if r.Method == http.MethodGet {
+			err = svc.VerifyRequest(ctx, nil)
+			if err == nil {
+				body, err = queryBody(r.URL.Query())
+			}
+		} else if method.NDJSON && len(svc.RequestVerifiers) == 0 && r.Header.Get("Content-Encoding") == "" {
+			// records are read from the connection as the handler consumes them
+			ctx = rpcservice.WithNDJSONBody(ctx, r.Body)
+		} else {
+			body, err = ioutil.ReadAll(r.Body)
+			if err != nil {
+				sendHTTPError(w, r, hand.New(runtime.ErrCodeInvalidBody))
+				return
+			}
+			err = svc.VerifyRequest(ctx, body)
+			if err == nil {
+				body, err = svc.DecompressRequestBody(r.Header.Get("Content-Encoding"), body)
+			}
+			if err == nil {
+				body, err = svc.DecodeRequestBody(r.Header.Get("Content-Type"), body)
+			}
+		}
 		if err != nil {
-			sendHTTPError(w, hand.New(runtime.ErrCodeInvalidBody))
+			sendHTTPError(w, r, err)
+			return
+		}
+
+		token := r.Header.Get("authorization")
+		if token == "" {
+			token = queryToken
+		}
+		authn := s.authn
+
+		if token == "" && s.cookieAuthn != nil {
+			if cookie, err := r.Cookie(s.cookieAuthn.Name); err == nil {
+				token = cookie.Value
+				authn = s.cookieAuthn
+			}
+		}
+
+		if token == "" && svc.RequiresAuthentication(method) {
+			err := hand.New(runtime.ErrCodeNoAuthentication)
+
+			reqLogger.Entry().
+				WithError(err).
+				Warn("devserver: authentication required")
+
+			sendHTTPError(w, r, err)
 			return
 		}
 
-		if svc.IdentityProvider != nil {
-			token := r.Header.Get("authorization")
+		// a token is always verified when the method requires authentication, even if the service does not use the
+		// identity, so that an unverified header or cookie is never treated as authenticated
+		if svc.RequiresAuthentication(method) || (svc.UsesIdentity() && !(token == "" && method.AllowAnonymous)) {
 			if token == "" {
 				err := hand.New("authentication_required")
 
@@ -113,95 +392,293 @@ func wrapRPCMethod(svc *rpcservice.Service, method *rpcservice.Method, authn *au
 					WithError(err).
 					Warn("devserver: jwt auth required")
 
-				sendHTTPError(w, err)
+				sendHTTPError(w, r, err)
 				return
 			}
 
-			var atclaims map[string]interface{}
-			err := authn.Authenticate(r.Context(), token, &atclaims)
+			atclaims, err := authenticate(r, authn, token)
 			if err != nil {
 				reqLogger.Entry().
 					WithError(err).
 					Warn("devserver: jwt auth failed")
 
-				sendHTTPError(w, err)
+				sendHTTPError(w, r, err)
 				return
 			}
 
-			ctx = svc.IdentityProvider(ctx, atclaims)
+			if !s.RedactIdentity {
+				reqLogger.Update(reqLogger.Entry().WithFields(logrus.Fields{
+					"subject": atclaims["sub"],
+					"scopes":  atclaims["scope"],
+				}))
+			}
+
+			ctx = svc.ProvideIdentity(ctx, atclaims)
 		}
 
-		for _, fn := range svc.ContextProviders {
-			ctx = fn(ctx)
+		ctx, err = svc.ProvideContext(ctx)
+		if err != nil {
+			entry := reqLogger.Entry().WithError(err)
+			if handErr, ok := err.(hand.E); ok {
+				entry = entry.WithFields(logrus.Fields(handErr.LogMeta))
+				if handErr.Err != nil {
+					entry = entry.WithField("err_cause", handErr.CauseDetail())
+				}
+			}
+			entry.Warn("devserver: context provider failed")
+
+			sendHTTPError(w, r, err)
+			return
+		}
+
+		result, err := svc.Invoke(ctx, method, body)
+
+		if s.recorder != nil {
+			s.recorder.record(reqLogger, method, body, result, err)
+		}
+
+		for key, values := range runtime.ResponseHeaders(ctx) {
+			for _, v := range values {
+				w.Header().Add(key, v)
+			}
 		}
 
-		result, err := method.Invoke(ctx, body)
 		if err != nil {
-			sendHTTPError(w, err)
+			sendHTTPError(w, r, err)
 			return
 		}
 
+		if runtime.NotModified(ctx, r.Header.Get("If-None-Match")) {
+			setCORSHeaders(w)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		// a fallback can return a nil binary response inside its interface{} result
+		if bin, ok := result.(*rpcservice.BinaryResponse); ok && bin == nil {
+			result = nil
+		}
+
 		if result == nil {
 			setCORSHeaders(w)
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
-		resBytes, err := json.Marshal(result)
+		if bin, ok := result.(*rpcservice.BinaryResponse); ok {
+			if err := svc.CheckResponseSize(ctx, method, len(bin.Body)); err != nil {
+				sendHTTPError(w, r, err)
+				return
+			}
+
+			setCORSHeaders(w)
+			w.Header().Set("Content-Type", bin.ContentType)
+			w.WriteHeader(method.SuccessStatusCode())
+			w.Write(bin.Body)
+			return
+		}
+
+		if redirect, ok := result.(*runtime.Redirect); ok {
+			setCORSHeaders(w)
+			w.Header().Set("Location", redirect.URL)
+			w.WriteHeader(redirect.RedirectStatus())
+			return
+		}
+
+		if stream, ok := result.(*rpcservice.StreamResponse); ok {
+			setCORSHeaders(w)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(method.SuccessStatusCode())
+			writeStream(w, stream, reqLogger, svc.EnableEnvelope)
+			if svc.EnableEnvelope {
+				writeStreamWarnings(ctx, w, reqLogger)
+			}
+			return
+		}
+
+		var resBytes []byte
+		contentType := "application/json; charset=utf-8"
+
+		if svc.EnableMsgpack && !isPretty(r) {
+			resBytes, contentType, err = svc.EncodeResponseBody(r.Header.Get("Accept"), svc.EnvelopeResult(ctx, result))
+		} else {
+			resBytes, err = encodeJSON(r, svc.EnvelopeResult(ctx, result))
+		}
 		if err != nil {
 			reqLogger.Entry().WithError(err).Error("encoding response failed")
-			sendHTTPError(w, hand.New(runtime.ErrCodeUnknown))
+			sendHTTPError(w, r, hand.New(runtime.ErrCodeUnknown))
+			return
+		}
+		if err := svc.CheckResponseSize(ctx, method, len(resBytes)); err != nil {
+			sendHTTPError(w, r, err)
+			return
 		}
 
 		setCORSHeaders(w)
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(method.SuccessStatusCode())
 		w.Write(resBytes)
 	}
 }
 
+// takeQueryToken removes the access_token query parameter from the request URL and returns its value
+func takeQueryToken(r *http.Request) string {
+	query := r.URL.Query()
+	token := query.Get("access_token")
+	if token == "" {
+		return ""
+	}
+
+	query.Del("access_token")
+	r.URL.RawQuery = query.Encode()
+	r.RequestURI = r.URL.RequestURI()
+
+	return token
+}
+
+// authenticate verifies a token, failing when the server has no authenticator to verify it with
+func authenticate(r *http.Request, authn Authenticator, token string) (map[string]interface{}, error) {
+	if authn == nil {
+		return nil, hand.New(runtime.ErrCodeNoAuthentication).WithMessage("devserver has no authenticator")
+	}
+
+	var atclaims map[string]interface{}
+	if err := authn.Authenticate(r.Context(), token, &atclaims); err != nil {
+		return nil, err
+	}
+
+	return atclaims, nil
+}
+
+// queryBody converts query string parameters into a JSON request body. Values are strings, or arrays of strings
+// when a parameter is repeated; the reserved pretty parameter is ignored.
+func queryBody(query url.Values) ([]byte, error) {
+	doc := map[string]interface{}{}
+	for key, values := range query {
+		if key == "pretty" {
+			continue
+		}
+		if len(values) == 1 {
+			doc[key] = values[0]
+		} else {
+			doc[key] = values
+		}
+	}
+
+	if len(doc) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(doc)
+}
+
+func requestMeta(r *http.Request, headerNames []string) runtime.RequestMeta {
+	sourceIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		sourceIP = r.RemoteAddr
+	}
+
+	headers := map[string]string{}
+	for _, name := range headerNames {
+		if val := r.Header.Get(name); val != "" {
+			headers[http.CanonicalHeaderKey(name)] = val
+		}
+	}
+
+	return runtime.RequestMeta{
+		SourceIP:  sourceIP,
+		UserAgent: r.UserAgent(),
+		Headers:   headers,
+	}
+}
+
+// writeStream encodes stream items into a JSON array, flushing after each item.
+// With enveloped set, the array is opened as the data field of an envelope, which writeStreamWarnings closes.
+func writeStream(w http.ResponseWriter, stream *rpcservice.StreamResponse, reqLogger *logger.ContextSafeLogger, enveloped bool) {
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	if enveloped {
+		w.Write([]byte(`{"data":`))
+	}
+	w.Write([]byte("["))
+
+	first := true
+	for item := range stream.Items {
+		if !first {
+			w.Write([]byte(","))
+		}
+		first = false
+
+		if err := enc.Encode(item); err != nil {
+			reqLogger.Entry().WithError(err).Error("encoding stream item failed")
+			w.Write([]byte("null"))
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	w.Write([]byte("]"))
+}
+
+// writeStreamWarnings closes a streamed envelope, adding the warnings raised while the items were produced
+func writeStreamWarnings(ctx context.Context, w http.ResponseWriter, reqLogger *logger.ContextSafeLogger) {
+	if warnings := runtime.Warnings(ctx); len(warnings) > 0 {
+		b, err := json.Marshal(warnings)
+		if err != nil {
+			reqLogger.Entry().WithError(err).Error("encoding stream warnings failed")
+		} else {
+			w.Write([]byte(`,"warnings":`))
+			w.Write(b)
+		}
+	}
+
+	w.Write([]byte("}"))
+}
+
+func setDeprecationHeaders(w http.ResponseWriter, dep *rpcservice.Deprecation) {
+	w.Header().Set("Deprecation", "true")
+
+	if !dep.Sunset.IsZero() {
+		w.Header().Set("Sunset", dep.Sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
 func setCORSHeaders(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "DELETE,GET,HEAD,PUT,POST,PATCH,OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Authorization,Content-Type,Host,Origin,Accept")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization,Content-Type,Host,Origin,Accept,Accept-Version,If-None-Match")
 }
 
-func optionsHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) optionsHandler(w http.ResponseWriter, r *http.Request) {
 	setCORSHeaders(w)
+	if s.CORSMaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(s.CORSMaxAge.Seconds())))
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func sendHTTPError(w http.ResponseWriter, err error) {
-	var status int
+func sendHTTPError(w http.ResponseWriter, r *http.Request, err error) {
+	svc, hasService := r.Context().Value(serviceKey).(*rpcservice.Service)
+	if hasService {
+		err = svc.RedactError(err)
+	}
 
 	handErr, ok := err.(hand.E)
 	if !ok {
 		handErr = hand.New(runtime.ErrCodeUnknown)
 	}
 
-	switch handErr.Code {
-	case runtime.ErrCodeBadRequest:
-		fallthrough
-	case runtime.ErrCodeInvalidBody:
-		fallthrough
-	case runtime.ErrCodeSchemaFailure:
-		fallthrough
-	case runtime.ErrCodeMissingBody:
-		status = http.StatusBadRequest
-
-	case runtime.ErrCodeForbidden:
-		status = http.StatusForbidden
-
-	case runtime.ErrCodeNoAuthentication:
-		fallthrough
-	case runtime.ErrCodeInvalidAuthentication:
-		status = http.StatusUnauthorized
+	status := runtime.HTTPStatus(handErr.Code)
 
-	default:
-		status = http.StatusInternalServerError
+	var res interface{} = handErr
+	if hasService {
+		res = svc.EnvelopeError(handErr)
 	}
 
-	body, err := json.Marshal(handErr)
+	body, err := encodeJSON(r, res)
 	if err != nil {
 		body = []byte(`{"code":"error_serialisation_fail"}`)
 	}
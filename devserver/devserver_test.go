@@ -0,0 +1,160 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-wilson/runtime/auth"
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/sirupsen/logrus"
+)
+
+type pingResponse struct {
+	Subject string `json:"subject"`
+}
+
+func ping(ctx context.Context) (*pingResponse, error) {
+	res := &pingResponse{}
+	if claims, ok := auth.GetIdentityContext(ctx); ok {
+		res.Subject = claims.Subject
+	}
+
+	return res, nil
+}
+
+func testLogger() *logrus.Entry {
+	l := logrus.New()
+	l.Out = ioutil.Discard
+
+	return logrus.NewEntry(l)
+}
+
+func testAuthenticator() *MockAuthenticator {
+	return &MockAuthenticator{
+		Insecure: true,
+		Tokens: map[string]auth.Claims{
+			"valid": {Subject: "user_1"},
+		},
+	}
+}
+
+func newTestServer(authn Authenticator, svc *rpcservice.Service) *Server {
+	s := New(":0", authn)
+	s.Log = testLogger()
+
+	return s.AddService("svc", svc)
+}
+
+func serve(s *Server, req *http.Request) *httptest.ResponseRecorder {
+	if req.Header.Get("Content-Type") == "" && req.ContentLength > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := httptest.NewRecorder()
+	s.r.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func postRPC(path, body string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	for key, val := range headers {
+		req.Header.Set(key, val)
+	}
+
+	return req
+}
+
+// assertErrorCode checks a response failed with the error code, or succeeded when code is empty
+func assertErrorCode(t *testing.T, rec *httptest.ResponseRecorder, code string) {
+	t.Helper()
+
+	if code == "" {
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected success, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return
+	}
+
+	var res struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("expected json error body, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Code < 400 || res.Code != code {
+		t.Fatalf("expected error %s, got %d: %s", code, rec.Code, rec.Body.String())
+	}
+}
+
+func TestRequireAuthenticationVerifiesToken(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		WithRequireAuthentication().
+		AddMethod("ping", ping, nil)
+	s := newTestServer(testAuthenticator(), svc)
+
+	tests := []struct {
+		name  string
+		token string
+		code  string
+	}{
+		{"no token", "", "no_authentication"},
+		{"unverifiable token", "garbage", "invalid_token"},
+		{"valid token", "valid", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.token != "" {
+				headers["Authorization"] = tt.token
+			}
+
+			assertErrorCode(t, serve(s, postRPC("/svc/ping", "", headers)), tt.code)
+		})
+	}
+}
+
+func TestCookieAuthenticationVerifiesSignature(t *testing.T) {
+	cookies := &CookieAuthenticator{Name: "session", Key: []byte("test-key")}
+
+	svc := rpcservice.NewService(testLogger()).
+		WithRequireAuthentication().
+		AddMethod("ping", ping, nil)
+	s := newTestServer(testAuthenticator(), svc).WithCookieAuthenticator(cookies)
+
+	signed, err := cookies.Sign(auth.Claims{Subject: "user_1"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := (&CookieAuthenticator{Name: "session", Key: []byte("other-key")}).Sign(auth.Claims{Subject: "user_1"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		cookie string
+		code   string
+	}{
+		{"arbitrary value", "anything", "invalid_token"},
+		{"signed with another key", forged, "invalid_token"},
+		{"signed", signed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := postRPC("/svc/ping", "", nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: tt.cookie})
+
+			assertErrorCode(t, serve(s, req), tt.code)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+type envelopeBody struct {
+	Data     json.RawMessage   `json:"data"`
+	Error    *json.RawMessage  `json:"error"`
+	Warnings []runtime.Warning `json:"warnings"`
+}
+
+func decodeEnvelope(t *testing.T, body []byte) envelopeBody {
+	var env envelopeBody
+	if err := json.Unmarshal(body, &env); err != nil {
+		t.Fatalf("expected an envelope, got %s: %v", body, err)
+	}
+
+	return env
+}
+
+func countdown(ctx context.Context) (*rpcservice.StreamResponse, error) {
+	items := make(chan interface{})
+
+	go func() {
+		defer close(items)
+		for i := 3; i > 0; i-- {
+			items <- i
+		}
+		runtime.AddWarning(ctx, "slow_countdown", "the countdown took a while")
+	}()
+
+	return &rpcservice.StreamResponse{Items: items}, nil
+}
+
+func TestEnvelopeStream(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		WithEnvelope().
+		AddMethod("countdown", countdown, nil)
+
+	rec := serve(newTestServer(nil, svc), postRPC("/svc/countdown", "", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	env := decodeEnvelope(t, rec.Body.Bytes())
+
+	var items []int
+	if err := json.Unmarshal(env.Data, &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0] != 3 || items[2] != 1 {
+		t.Fatalf("expected streamed items under data, got %v", items)
+	}
+	if len(env.Warnings) != 1 || env.Warnings[0].Code != "slow_countdown" {
+		t.Fatalf("expected the warning raised while streaming, got %+v", env.Warnings)
+	}
+}
+
+func TestEnvelopeServiceErrors(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		WithEnvelope().
+		AddMethod("ping", ping, nil)
+	s := newTestServer(nil, svc)
+
+	unsupported := postRPC("/svc/ping", "<ping/>", map[string]string{"Content-Type": "application/xml"})
+	notFound := postRPC("/svc/missing", "", nil)
+
+	for name, req := range map[string]*http.Request{"unsupported media type": unsupported, "method not found": notFound} {
+		t.Run(name, func(t *testing.T) {
+			rec := serve(s, req)
+
+			env := decodeEnvelope(t, rec.Body.Bytes())
+			if env.Error == nil {
+				t.Fatalf("expected error under the envelope, got %s", rec.Body.String())
+			}
+		})
+	}
+}
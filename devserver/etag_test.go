@@ -0,0 +1,29 @@
+package devserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+func TestETag(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		AddMethod("ping", func(ctx context.Context) (*pingResponse, error) {
+			runtime.SetETag(ctx, "v1")
+			return ping(ctx)
+		}, nil)
+	s := newTestServer(nil, svc)
+
+	rec := serve(s, postRPC("/svc/ping", "", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"v1"` {
+		t.Fatalf("expected the response to carry its ETag, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = serve(s, postRPC("/svc/ping", "", map[string]string{"If-None-Match": `"v1"`}))
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("expected 304 without a body, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,145 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+type itemRequest struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type itemResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func itemHandler(ctx context.Context, req *itemRequest) (*itemResponse, error) {
+	return &itemResponse{ID: req.ID, Name: req.Name}, nil
+}
+
+var itemSchema = gojsonschema.NewStringLoader(`{
+	"type": "object",
+	"required": ["id"],
+	"properties": {"id": {"type": "string"}, "name": {"type": "string"}}
+}`)
+
+func verbServer() *Server {
+	svc := rpcservice.NewService(testLogger())
+
+	for name, verb := range map[string]string{
+		"getItem":    http.MethodGet,
+		"putItem":    http.MethodPut,
+		"patchItem":  http.MethodPatch,
+		"deleteItem": http.MethodDelete,
+	} {
+		svc.AddMethod(name, itemHandler, itemSchema)
+		method, _ := svc.GetMethod(name)
+		method.WithHTTPVerb(strings.ToLower(verb))
+	}
+
+	return newTestServer(testAuthenticator(), svc)
+}
+
+func decodeItem(t *testing.T, rec *httptest.ResponseRecorder) itemResponse {
+	t.Helper()
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected success, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var res itemResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestHTTPVerbs(t *testing.T) {
+	s := verbServer()
+
+	t.Run("GET reads the query string", func(t *testing.T) {
+		res := decodeItem(t, serve(s, httptest.NewRequest(http.MethodGet, "/svc/getItem?id=item_1&name=lamp", nil)))
+		if res.ID != "item_1" || res.Name != "lamp" {
+			t.Fatalf("expected the query parameters as the request, got %+v", res)
+		}
+	})
+
+	t.Run("GET validates the query string", func(t *testing.T) {
+		rec := serve(s, httptest.NewRequest(http.MethodGet, "/svc/getItem?name=lamp", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected the missing id to fail validation, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	for _, tt := range []struct {
+		verb   string
+		method string
+	}{
+		{http.MethodPut, "putItem"},
+		{http.MethodPatch, "patchItem"},
+		{http.MethodDelete, "deleteItem"},
+	} {
+		t.Run(tt.verb+" reads the body", func(t *testing.T) {
+			req := httptest.NewRequest(tt.verb, "/svc/"+tt.method, strings.NewReader(`{"id": "item_1", "name": "lamp"}`))
+			if res := decodeItem(t, serve(s, req)); res.ID != "item_1" || res.Name != "lamp" {
+				t.Fatalf("expected the body as the request, got %+v", res)
+			}
+		})
+	}
+
+	t.Run("POST is accepted for every verb", func(t *testing.T) {
+		for _, method := range []string{"getItem", "putItem", "patchItem", "deleteItem"} {
+			decodeItem(t, serve(s, postRPC("/svc/"+method, `{"id": "item_1"}`, nil)))
+		}
+	})
+
+	t.Run("other verbs are rejected", func(t *testing.T) {
+		for _, tt := range []struct {
+			verb   string
+			method string
+		}{
+			{http.MethodDelete, "getItem"},
+			{http.MethodGet, "putItem"},
+			{http.MethodPut, "patchItem"},
+			{http.MethodPatch, "deleteItem"},
+		} {
+			req := httptest.NewRequest(tt.verb, "/svc/"+tt.method, strings.NewReader(`{"id": "item_1"}`))
+			if rec := serve(s, req); rec.Code != http.StatusMethodNotAllowed {
+				t.Errorf("%s %s: expected 405, got %d: %s", tt.verb, tt.method, rec.Code, rec.Body.String())
+			}
+		}
+	})
+}
+
+func TestHTTPVerbInSchema(t *testing.T) {
+	rec := serve(verbServer(), httptest.NewRequest(http.MethodGet, "/svc/_schema", nil))
+
+	var doc struct {
+		Methods []struct {
+			Name     string `json:"name"`
+			HTTPVerb string `json:"http_verb"`
+		} `json:"methods"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("%v: %s", err, rec.Body.String())
+	}
+
+	verbs := map[string]string{}
+	for _, m := range doc.Methods {
+		verbs[m.Name] = m.HTTPVerb
+	}
+	if verbs["getItem"] != http.MethodGet || verbs["deleteItem"] != http.MethodDelete {
+		t.Fatalf("expected the declared verbs in the schema, got %v", verbs)
+	}
+}
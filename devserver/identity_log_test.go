@@ -0,0 +1,51 @@
+package devserver
+
+import (
+	"testing"
+
+	"github.com/g-wilson/runtime/auth"
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestIdentityLogFields(t *testing.T) {
+	authn := &MockAuthenticator{
+		Insecure: true,
+		Tokens: map[string]auth.Claims{
+			"valid": {Subject: "user_1", Scope: "read write"},
+		},
+	}
+
+	for _, redact := range []bool{false, true} {
+		log, hook := test.NewNullLogger()
+
+		svc := rpcservice.NewService(logrus.NewEntry(log)).
+			WithIdentityProvider(identityFromClaims).
+			AddMethod("ping", ping, nil)
+		s := newTestServer(authn, svc)
+		s.RedactIdentity = redact
+
+		assertErrorCode(t, serve(s, postRPC("/svc/ping", "", map[string]string{"Authorization": "valid"})), "")
+
+		var completion *logrus.Entry
+		for _, entry := range hook.AllEntries() {
+			if entry.Message == "rpc request completed" {
+				completion = entry
+			}
+		}
+		if completion == nil {
+			t.Fatal("expected the request to be logged")
+		}
+
+		_, hasSubject := completion.Data["subject"]
+		_, hasScopes := completion.Data["scopes"]
+		if redact && (hasSubject || hasScopes) {
+			t.Fatalf("expected the identity to be redacted from logs, got %v", completion.Data)
+		}
+		if !redact && (completion.Data["subject"] != "user_1" || completion.Data["scopes"] != "read write") {
+			t.Fatalf("expected the subject and scopes in logs, got %v", completion.Data)
+		}
+	}
+}
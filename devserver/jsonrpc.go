@@ -0,0 +1,83 @@
+package devserver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/logger"
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/go-chi/chi"
+)
+
+// AddJSONRPCService serves an RPC Service as a JSON-RPC 2.0 endpoint at a single HTTP path on the server's router
+func (s *Server) AddJSONRPCService(path string, svc *rpcservice.Service) *Server {
+	s.services = append(s.services, svc)
+
+	s.r.Route(fmt.Sprintf("/%s", path), func(r chi.Router) {
+		r.Use(attachRequestLogger(svc.Logger))
+		r.Options("/", s.optionsHandler)
+		r.Post("/", wrapJSONRPC(svc, s.authn))
+	})
+
+	return s
+}
+
+func wrapJSONRPC(svc *rpcservice.Service, authn Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		reqLogger := logger.FromContext(ctx)
+
+		if r.Body == nil {
+			sendHTTPError(w, r, hand.New(runtime.ErrCodeMissingBody))
+			return
+		}
+		defer r.Body.Close()
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			sendHTTPError(w, r, hand.New(runtime.ErrCodeInvalidBody))
+			return
+		}
+
+		ctx = runtime.WithRequestMeta(ctx, requestMeta(r, svc.RequestMetaHeaders))
+		ctx = rpcservice.WithAcceptVersion(ctx, r.Header.Get("Accept-Version"))
+
+		token := r.Header.Get("authorization")
+		authenticated := false
+
+		if token != "" {
+			atclaims, err := authenticate(r, authn, token)
+			if err != nil {
+				reqLogger.Entry().
+					WithError(err).
+					Warn("devserver: jwt auth failed")
+
+				writeJSONRPC(w, svc.JSONRPCErrorResponse(body, err))
+				return
+			}
+
+			authenticated = true
+			ctx = svc.ProvideIdentity(ctx, atclaims)
+		}
+
+		writeJSONRPC(w, svc.HandleJSONRPC(ctx, body, authenticated))
+	}
+}
+
+// writeJSONRPC writes an encoded JSON-RPC response, or no content when the request contained only notifications
+func writeJSONRPC(w http.ResponseWriter, resBytes []byte) {
+
+	setCORSHeaders(w)
+
+	if resBytes == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resBytes)
+}
@@ -0,0 +1,111 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+func TestJSONRPCVerifiesToken(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		WithRequireAuthentication().
+		AddMethod("ping", ping, nil)
+
+	s := New(":0", testAuthenticator())
+	s.Log = testLogger()
+	s.AddJSONRPCService("rpc", svc)
+
+	body := `{"jsonrpc":"2.0","method":"ping","id":1}`
+
+	t.Run("unverifiable token", func(t *testing.T) {
+		rec := serve(s, postRPC("/rpc/", body, map[string]string{"Authorization": "garbage"}))
+
+		var res struct {
+			JSONRPC string `json:"jsonrpc"`
+			Error   *struct {
+				Data struct {
+					Code string `json:"code"`
+				} `json:"data"`
+			} `json:"error"`
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.JSONRPC != "2.0" || res.Error == nil || res.Error.Data.Code != "invalid_token" || string(res.ID) != "1" {
+			t.Fatalf("expected a JSON-RPC invalid_token error for request 1, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		rec := serve(s, postRPC("/rpc/", body, nil))
+
+		var res struct {
+			Error *struct {
+				Data struct {
+					Code string `json:"code"`
+				} `json:"data"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Error == nil || res.Error.Data.Code != "no_authentication" {
+			t.Fatalf("expected no_authentication error, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		rec := serve(s, postRPC("/rpc/", body, map[string]string{"Authorization": "valid"}))
+
+		var res struct {
+			Result *pingResponse `json:"result"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Result == nil {
+			t.Fatalf("expected a result, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestJSONRPCAcceptVersion(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		AddMethod("ping", ping, nil).
+		AddMethodVersion("ping", "2", func(ctx context.Context) (*pingResponse, error) {
+			return &pingResponse{Subject: "v2"}, nil
+		}, nil)
+
+	s := New(":0", testAuthenticator())
+	s.Log = testLogger()
+	s.AddJSONRPCService("rpc", svc)
+
+	body := `{"jsonrpc":"2.0","method":"ping","id":1}`
+
+	tests := map[string]string{
+		"":  "v2",
+		"1": "",
+	}
+
+	for version, expected := range tests {
+		headers := map[string]string{}
+		if version != "" {
+			headers["Accept-Version"] = version
+		}
+
+		rec := serve(s, postRPC("/rpc/", body, headers))
+
+		var res struct {
+			Result *pingResponse `json:"result"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Result == nil || res.Result.Subject != expected {
+			t.Fatalf("version %q: expected %q, got %s", version, expected, rec.Body.String())
+		}
+	}
+}
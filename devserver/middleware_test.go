@@ -0,0 +1,119 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type regionKey struct{}
+
+type regionResponse struct {
+	Region string `json:"region"`
+}
+
+// requireRegion is a cross-cutting check written once and installed on the service, so every transport runs it
+func requireRegion(ctx context.Context) (context.Context, error) {
+	meta, _ := runtime.RequestMetaFromContext(ctx)
+
+	region := meta.Headers["X-Region"]
+	if region == "" {
+		return ctx, hand.New(runtime.ErrCodeBadRequest).WithMessage("region header required")
+	}
+
+	return context.WithValue(ctx, regionKey{}, region), nil
+}
+
+func region(ctx context.Context) (*regionResponse, error) {
+	region, _ := ctx.Value(regionKey{}).(string)
+	return &regionResponse{Region: region}, nil
+}
+
+func TestContextProviderRunsOnEveryTransport(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		WithRequestMetaHeaders("X-Region").
+		WithFallibleContextProvider(requireRegion).
+		AddMethod("region", region, nil)
+
+	devserverCall := func(header string) (int, string) {
+		headers := map[string]string{}
+		if header != "" {
+			headers["X-Region"] = header
+		}
+
+		rec := serve(newTestServer(nil, svc), postRPC("/svc/region", "", headers))
+		return rec.Code, rec.Body.String()
+	}
+
+	lambdaCall := func(header string) (int, string) {
+		event := events.APIGatewayV2HTTPRequest{
+			PathParameters: map[string]string{"method": "region"},
+			Headers:        map[string]string{},
+		}
+		if header != "" {
+			event.Headers["x-region"] = header
+		}
+
+		res, err := svc.WrapAPIGatewayHTTP()(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, res.Body
+	}
+
+	transports := map[string]func(header string) (int, string){
+		"devserver": devserverCall,
+		"lambda":    lambdaCall,
+	}
+
+	for name, call := range transports {
+		t.Run(name, func(t *testing.T) {
+			status, body := call("")
+			if status != http.StatusBadRequest {
+				t.Fatalf("expected request without region to be rejected, got %d: %s", status, body)
+			}
+
+			status, body = call("eu-west-1")
+			if status != http.StatusOK {
+				t.Fatalf("expected request with region to succeed, got %d: %s", status, body)
+			}
+
+			var res regionResponse
+			if err := json.Unmarshal([]byte(body), &res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Region != "eu-west-1" {
+				t.Fatalf("expected handler to see the provided region, got %q", res.Region)
+			}
+		})
+	}
+}
+
+func TestContextProviderErrorsRedacted(t *testing.T) {
+	const secret = "connecting to db.internal:5432 failed"
+
+	svc := rpcservice.NewService(testLogger()).
+		WithFallibleContextProvider(func(ctx context.Context) (context.Context, error) {
+			return ctx, hand.New(runtime.ErrCodeUnknown).WithMessage(secret)
+		}).
+		AddMethod("ping", ping, nil)
+
+	rec := serve(newTestServer(nil, svc), postRPC("/svc/ping", "", nil))
+	assertErrorCode(t, rec, runtime.ErrCodeUnknown)
+	if strings.Contains(rec.Body.String(), secret) {
+		t.Fatalf("expected the server error message to be redacted, got %s", rec.Body.String())
+	}
+
+	rec = serve(newTestServer(nil, svc.WithEnvironment(rpcservice.EnvironmentDevelopment)), postRPC("/svc/ping", "", nil))
+	if !strings.Contains(rec.Body.String(), secret) {
+		t.Fatalf("expected the message in development, got %s", rec.Body.String())
+	}
+}
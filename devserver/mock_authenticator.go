@@ -0,0 +1,47 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/auth"
+	"github.com/g-wilson/runtime/hand"
+)
+
+// Authenticator validates an access token and scans its claims into dest
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string, dest interface{}) error
+}
+
+// MockAuthenticator accepts static tokens and maps them to known claims without any verification.
+// It refuses every token unless Insecure is explicitly set, and must never be used outside local development.
+type MockAuthenticator struct {
+	Insecure bool
+	// Tokens maps exact Authorization header values to claims
+	Tokens map[string]auth.Claims
+	// AnyToken, when set, is used for any token not found in Tokens
+	AnyToken *auth.Claims
+}
+
+// Authenticate implements Authenticator
+func (m *MockAuthenticator) Authenticate(ctx context.Context, token string, dest interface{}) error {
+	if !m.Insecure {
+		return hand.New(runtime.ErrCodeInvalidAuthentication).WithMessage("mock authenticator is not enabled")
+	}
+
+	claims, ok := m.Tokens[token]
+	if !ok {
+		if m.AnyToken == nil {
+			return hand.New(runtime.ErrCodeInvalidToken).WithMessage("unknown mock token")
+		}
+		claims = *m.AnyToken
+	}
+
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, dest)
+}
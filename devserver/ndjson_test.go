@@ -0,0 +1,62 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+type importResponse struct {
+	Imported   int                          `json:"imported"`
+	LineErrors []rpcservice.NDJSONLineError `json:"line_errors"`
+}
+
+func importRecords(ctx context.Context, records *rpcservice.NDJSONReader) (*importResponse, error) {
+	res := &importResponse{}
+	for records.Next() {
+		var record map[string]interface{}
+		if err := records.Decode(&record); err == nil {
+			res.Imported++
+		}
+	}
+	res.LineErrors = records.LineErrors()
+
+	return res, records.Err()
+}
+
+func TestNDJSONStreamedBody(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		WithRequestLimits(3, 10).
+		AddNDJSONMethod("import", importRecords, gojsonschema.NewStringLoader(`{"type": "object", "required": ["name"]}`))
+
+	body := strings.Join([]string{
+		`{"name": "alice", "tags": ["a", "b"]}`,
+		`{"email": "bob@example.com"}`,
+		`{"name": "carol", "tags": ["c", "d"]}`,
+		`{"name": "dave", "tags": [[[["e"]]]]}`,
+	}, "\n")
+
+	req := postRPC("/svc/import", body, map[string]string{"Content-Type": rpcservice.ContentTypeNDJSON})
+	rec := serve(newTestServer(nil, svc), req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var res importResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Imported != 2 {
+		t.Fatalf("expected the valid records to be imported, got %d", res.Imported)
+	}
+	if len(res.LineErrors) != 2 || res.LineErrors[0].Line != 2 || res.LineErrors[1].Line != 4 {
+		t.Fatalf("expected the record missing a name and the record over the depth limit to be rejected, got %+v", res.LineErrors)
+	}
+}
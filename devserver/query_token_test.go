@@ -0,0 +1,103 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/g-wilson/runtime/auth"
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+type searchRequest struct {
+	Query       string `json:"q"`
+	AccessToken string `json:"access_token"`
+}
+
+type searchResponse struct {
+	Subject     string `json:"subject"`
+	AccessToken string `json:"access_token"`
+}
+
+func search(ctx context.Context, req *searchRequest) (*searchResponse, error) {
+	res := &searchResponse{AccessToken: req.AccessToken}
+	if claims, ok := auth.GetIdentityContext(ctx); ok {
+		res.Subject = claims.Subject
+	}
+
+	return res, nil
+}
+
+func identityFromClaims(ctx context.Context, claims map[string]interface{}) context.Context {
+	identity, err := auth.ClaimsFromMap(claims)
+	if err != nil {
+		return ctx
+	}
+
+	return auth.SetIdentityContext(ctx, identity)
+}
+
+func queryTokenServer(enabled bool) *Server {
+	svc := rpcservice.NewService(testLogger()).
+		WithRequireAuthentication().
+		WithIdentityProvider(identityFromClaims).
+		AddMethod("ping", ping, nil).
+		AddMethod("search", search, gojsonschema.NewStringLoader(`{"type": "object"}`))
+
+	method, _ := svc.GetMethod("search")
+	method.WithHTTPVerb(http.MethodGet)
+
+	s := newTestServer(testAuthenticator(), svc)
+	s.QueryTokenAuth = enabled
+
+	return s
+}
+
+func TestQueryTokenAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		path    string
+		code    string
+	}{
+		{"disabled", false, "/svc/ping?access_token=valid", "no_authentication"},
+		{"enabled with valid token", true, "/svc/ping?access_token=valid", ""},
+		{"enabled with invalid token", true, "/svc/ping?access_token=garbage", "invalid_token"},
+		{"enabled without token", true, "/svc/ping", "no_authentication"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertErrorCode(t, serve(queryTokenServer(tt.enabled), postRPC(tt.path, "", nil)), tt.code)
+		})
+	}
+}
+
+func TestQueryTokenAuthPrefersHeader(t *testing.T) {
+	rec := serve(queryTokenServer(true), postRPC("/svc/ping?access_token=garbage", "", map[string]string{"Authorization": "valid"}))
+	assertErrorCode(t, rec, "")
+}
+
+func TestQueryTokenRemovedFromRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/svc/search?q=shoes&access_token=valid", nil)
+	rec := serve(queryTokenServer(true), req)
+	assertErrorCode(t, rec, "")
+
+	var res searchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Subject != "user_1" {
+		t.Fatalf("expected the query token to authenticate the request, got %+v", res)
+	}
+	if res.AccessToken != "" {
+		t.Fatal("expected the access token to be removed from the query before it reaches the handler")
+	}
+	if req.URL.Query().Get("access_token") != "" {
+		t.Fatalf("expected the access token to be scrubbed from the request URL, got %s", req.URL)
+	}
+}
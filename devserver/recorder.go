@@ -0,0 +1,69 @@
+package devserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/logger"
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+type recorder struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+type recording struct {
+	Method   string          `json:"method"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response interface{}     `json:"response,omitempty"`
+	Status   int             `json:"status"`
+}
+
+// record writes a single request and its outcome as a JSON line. Failures are logged and never affect the response.
+func (rec *recorder) record(reqLogger *logger.ContextSafeLogger, method *rpcservice.Method, body []byte, result interface{}, err error) {
+	entry := recording{
+		Method:   method.Name,
+		Response: result,
+		Status:   method.SuccessStatusCode(),
+	}
+
+	// stream items are consumed by the live response, so only the status is recorded
+	if _, ok := result.(*rpcservice.StreamResponse); ok {
+		entry.Response = nil
+	}
+
+	if json.Valid(body) {
+		entry.Request = body
+	}
+
+	if err != nil {
+		handErr, ok := err.(hand.E)
+		if !ok {
+			handErr = hand.New(runtime.ErrCodeUnknown)
+		}
+		entry.Response = handErr
+		entry.Status = runtime.HTTPStatus(handErr.Code)
+	} else if result == nil {
+		entry.Status = http.StatusNoContent
+	} else if redirect, ok := result.(*runtime.Redirect); ok {
+		entry.Status = redirect.RedirectStatus()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		reqLogger.Entry().WithError(err).Warn("devserver: recording request failed")
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if _, err := rec.w.Write(append(line, '\n')); err != nil {
+		reqLogger.Entry().WithError(err).Warn("devserver: recording request failed")
+	}
+}
@@ -0,0 +1,23 @@
+package devserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+func TestRedirectResponse(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		AddMethod("callback", func(ctx context.Context) (*runtime.Redirect, error) {
+			return &runtime.Redirect{URL: "https://app.example.com/signed-in"}, nil
+		}, nil)
+
+	rec := serve(newTestServer(nil, svc), postRPC("/svc/callback", "", nil))
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://app.example.com/signed-in" {
+		t.Fatalf("expected a 302 redirect, got %d %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,51 @@
+package devserver
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+func TestTrailingSlashes(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		AddMethod("ping", ping, nil).
+		AddMethod("users.create", ping, nil)
+
+	tests := []struct {
+		path  string
+		strip bool
+		code  int
+	}{
+		{"/svc/ping", false, http.StatusOK},
+		{"/svc/ping/", false, http.StatusNotFound},
+		{"/svc/ping/", true, http.StatusOK},
+		{"/svc/users/create", false, http.StatusOK},
+		{"/svc/users/create/", false, http.StatusNotFound},
+		{"/svc/users/create/", true, http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		s := newTestServer(nil, svc)
+		s.StripTrailingSlashes = tc.strip
+
+		rec := serve(s, postRPC(tc.path, "", nil))
+		if rec.Code != tc.code {
+			t.Errorf("%s with StripTrailingSlashes %t: expected %d, got %d", tc.path, tc.strip, tc.code, rec.Code)
+		}
+	}
+}
+
+func TestCaseInsensitiveMethods(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).AddMethod("getUser", ping, nil)
+
+	s := newTestServer(nil, svc)
+	if rec := serve(s, postRPC("/svc/GetUser", "", nil)); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected mismatched case to be not found by default, got %d", rec.Code)
+	}
+
+	s.CaseInsensitiveMethods = true
+	if rec := serve(s, postRPC("/svc/GetUser", "", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("expected mismatched case to resolve, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,93 @@
+package devserver
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+type schemaResponse struct {
+	Methods []schemaMethod `json:"methods"`
+}
+
+type schemaMethod struct {
+	Name       string            `json:"name"`
+	Idempotent bool              `json:"idempotent"`
+	HTTPVerb   string            `json:"http_verb,omitempty"`
+	Deprecated *schemaDeprecated `json:"deprecated,omitempty"`
+	Examples   []schemaExample   `json:"examples,omitempty"`
+}
+
+type schemaExample struct {
+	Request  interface{} `json:"request,omitempty"`
+	Response interface{} `json:"response,omitempty"`
+}
+
+type schemaDeprecated struct {
+	Sunset      string `json:"sunset,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// schemaHandler returns a handler which describes the methods registered on a service
+func schemaHandler(svc *rpcservice.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := schemaResponse{Methods: []schemaMethod{}}
+
+		for name, method := range svc.Methods {
+			sm := schemaMethod{Name: name, Idempotent: method.Idempotent, HTTPVerb: method.HTTPVerb}
+
+			if method.Deprecation != nil {
+				sm.Deprecated = &schemaDeprecated{Replacement: method.Deprecation.Replacement}
+				if !method.Deprecation.Sunset.IsZero() {
+					sm.Deprecated.Sunset = method.Deprecation.Sunset.UTC().Format(time.RFC3339)
+				}
+			}
+
+			for _, ex := range method.Examples {
+				sm.Examples = append(sm.Examples, schemaExample{Request: ex.Request, Response: ex.Response})
+			}
+
+			res.Methods = append(res.Methods, sm)
+		}
+
+		sort.Slice(res.Methods, func(i, j int) bool {
+			return res.Methods[i].Name < res.Methods[j].Name
+		})
+
+		resBytes, err := encodeJSON(r, res)
+		if err != nil {
+			sendHTTPError(w, r, hand.New(runtime.ErrCodeUnknown))
+			return
+		}
+
+		setCORSHeaders(w)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resBytes)
+	}
+}
+
+// statsHandler returns a handler which reports invocation counts for a service which has stats enabled
+func statsHandler(svc *rpcservice.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !svc.EnableStats {
+			sendHTTPError(w, r, hand.New(runtime.ErrCodeNotFound).WithMessage("stats are not enabled for this service"))
+			return
+		}
+
+		resBytes, err := encodeJSON(r, hand.M{"methods": svc.Stats()})
+		if err != nil {
+			sendHTTPError(w, r, hand.New(runtime.ErrCodeUnknown))
+			return
+		}
+
+		setCORSHeaders(w)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resBytes)
+	}
+}
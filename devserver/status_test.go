@@ -0,0 +1,108 @@
+package devserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		code   string
+		status int
+	}{
+		{runtime.ErrCodeConflict, http.StatusConflict},
+		{runtime.ErrCodeNotFound, http.StatusNotFound},
+		{"something_broke", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			svc := rpcservice.NewService(testLogger()).AddMethod("fail", func(ctx context.Context) error {
+				return hand.New(tt.code).WithMessage("failed")
+			}, nil)
+
+			rec := serve(newTestServer(nil, svc), postRPC("/svc/fail", "", nil))
+			assertErrorCode(t, rec, tt.code)
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
+
+func TestUnknownRoutesNotFound(t *testing.T) {
+	s := newTestServer(nil, rpcservice.NewService(testLogger()).AddMethod("ping", ping, nil))
+
+	for _, path := range []string{"/svc/missing", "/other/ping"} {
+		rec := serve(s, postRPC(path, "", nil))
+		assertErrorCode(t, rec, runtime.ErrCodeMethodNotFound)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		WithMaxResponseBytes(5).
+		AddMethod("ping", ping, nil)
+
+	assertErrorCode(t, serve(newTestServer(nil, svc), postRPC("/svc/ping", "", nil)), runtime.ErrCodeResponseTooLarge)
+}
+
+func TestFallback(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		AddMethod("ping", ping, nil).
+		SetFallback(func(ctx context.Context, methodName string, body []byte) (interface{}, error) {
+			return map[string]string{"method": methodName}, nil
+		})
+
+	rec := serve(newTestServer(nil, svc), postRPC("/svc/legacyMethod", "", nil))
+	assertErrorCode(t, rec, "")
+	if rec.Body.String() != `{"method":"legacyMethod"}` {
+		t.Fatalf("expected the fallback to receive the method name, got %s", rec.Body.String())
+	}
+}
+
+type createdResponse struct {
+	ID string `json:"id"`
+}
+
+func TestSuccessStatus(t *testing.T) {
+	svc := rpcservice.NewService(testLogger()).
+		AddMethod("create", func(ctx context.Context) (*createdResponse, error) {
+			return &createdResponse{ID: "item_1"}, nil
+		}, nil).
+		AddMethod("createNothing", func(ctx context.Context) (*createdResponse, error) {
+			return nil, nil
+		}, nil).
+		AddMethod("ping", ping, nil)
+
+	for _, name := range []string{"create", "createNothing"} {
+		method, _ := svc.GetMethod(name)
+		method.WithSuccessStatus(http.StatusCreated)
+	}
+
+	s := newTestServer(testAuthenticator(), svc)
+
+	tests := []struct {
+		method string
+		status int
+	}{
+		{"create", http.StatusCreated},
+		{"createNothing", http.StatusNoContent},
+		{"ping", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rec := serve(s, postRPC("/svc/"+tt.method, "", nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d: %s", tt.method, tt.status, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/rpcservice"
+)
+
+// claimsAuthenticator returns raw claims, including ones auth.Claims does not model such as a tenant
+type claimsAuthenticator map[string]interface{}
+
+func (a claimsAuthenticator) Authenticate(ctx context.Context, token string, dest interface{}) error {
+	b, err := json.Marshal(map[string]interface{}(a))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, dest)
+}
+
+type tenantResponse struct {
+	Tenant string `json:"tenant"`
+}
+
+func currentTenant(ctx context.Context) (*tenantResponse, error) {
+	tenant, _ := runtime.TenantFromContext(ctx)
+	return &tenantResponse{Tenant: tenant}, nil
+}
+
+func TestTenantExtractor(t *testing.T) {
+	authn := claimsAuthenticator{"sub": "user_1", "tenant": "acme"}
+	svc := rpcservice.NewService(testLogger()).
+		WithTenantExtractor(rpcservice.TenantClaim("tenant")).
+		AddMethod("currentTenant", currentTenant, nil)
+	s := newTestServer(authn, svc)
+
+	rec := serve(s, postRPC("/svc/currentTenant", "", map[string]string{"Authorization": "valid"}))
+	assertErrorCode(t, rec, "")
+
+	var body tenantResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%v: %s", err, rec.Body.String())
+	}
+	if body.Tenant != "acme" {
+		t.Fatalf("expected tenant acme, got %q", body.Tenant)
+	}
+
+	// the extractor needs claims, so the token is required even without an identity provider
+	assertErrorCode(t, serve(s, postRPC("/svc/currentTenant", "", nil)), "authentication_required")
+}
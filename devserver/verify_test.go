@@ -0,0 +1,30 @@
+package devserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	secret := []byte("whsec_test")
+	body := `{"q": "shoes"}`
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	svc := rpcservice.NewService(testLogger()).
+		WithWebhookSignature("X-Signature", secret).
+		AddMethod("search", search, gojsonschema.NewStringLoader(`{"type": "object"}`))
+	s := newTestServer(nil, svc)
+
+	assertErrorCode(t, serve(s, postRPC("/svc/search", body, map[string]string{"X-Signature": signature})), "")
+	assertErrorCode(t, serve(s, postRPC("/svc/search", `{"q": "boots"}`, map[string]string{"X-Signature": signature})), runtime.ErrCodeForbidden)
+}
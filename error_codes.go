@@ -11,3 +11,10 @@ const ErrCodeNoAuthentication = "no_authentication"
 const ErrCodeInvalidAuthentication = "invalid_authentication"
 const ErrCodeDownstream = "downstream_request_failed"
 const ErrCodeInvalidToken = "invalid_token"
+const ErrCodeRateLimited = "rate_limited"
+const ErrCodeConflict = "conflict"
+const ErrCodeNotFound = "not_found"
+const ErrCodeMethodNotFound = "method_not_found"
+const ErrCodeInsufficientTime = "insufficient_time_remaining"
+const ErrCodeResponseTooLarge = "response_too_large"
+const ErrCodeUnsupportedMediaType = "unsupported_media_type"
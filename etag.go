@@ -0,0 +1,33 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+)
+
+// SetETag sets the ETag response header, so execution environments can answer a matching If-None-Match with 304 Not Modified.
+// The tag is quoted if it is not already.
+func SetETag(ctx context.Context, tag string) {
+	if !strings.HasSuffix(tag, `"`) {
+		tag = `"` + tag + `"`
+	}
+
+	SetResponseHeader(ctx, "ETag", tag)
+}
+
+// NotModified reports whether the ETag set on the context matches the If-None-Match request header value
+func NotModified(ctx context.Context, ifNoneMatch string) bool {
+	etag := ResponseHeaders(ctx).Get("ETag")
+	if etag == "" || ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,34 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNotModified(t *testing.T) {
+	ctx := WithResponseHeaders(context.Background())
+	SetETag(ctx, "v1")
+
+	if etag := ResponseHeaders(ctx).Get("ETag"); etag != `"v1"` {
+		t.Fatalf("expected the tag to be quoted, got %s", etag)
+	}
+
+	tests := map[string]bool{
+		`"v1"`:       true,
+		`W/"v1"`:     true,
+		`"v0", "v1"`: true,
+		`*`:          true,
+		`"v2"`:       false,
+		``:           false,
+	}
+
+	for ifNoneMatch, expected := range tests {
+		if actual := NotModified(ctx, ifNoneMatch); actual != expected {
+			t.Errorf("If-None-Match %s: expected %t, got %t", ifNoneMatch, expected, actual)
+		}
+	}
+
+	if NotModified(WithResponseHeaders(context.Background()), `"v1"`) {
+		t.Fatal("expected requests without an ETag never to be not modified")
+	}
+}
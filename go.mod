@@ -5,9 +5,11 @@ go 1.13
 require (
 	github.com/aws/aws-lambda-go v1.16.0
 	github.com/go-chi/chi v4.1.0+incompatible
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	github.com/sirupsen/logrus v1.5.0
-	github.com/stretchr/testify v1.5.1 // indirect
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 	github.com/xeipuuv/gojsonschema v1.2.0
+	go.opentelemetry.io/otel/trace v1.0.0
 	golang.org/x/crypto v0.0.0-20200403201458-baeed622b8d8 // indirect
 	golang.org/x/net v0.0.0-20200324143707-d3edc9973b7e // indirect
 	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
@@ -11,30 +11,82 @@ type E struct {
 	Err     error  `json:"-"`
 	Message string `json:"message,omitempty"`
 	Meta    M      `json:"meta,omitempty"`
+	LogMeta M      `json:"-"`
 }
 
 func (h E) Error() string {
 	return h.Code
 }
 
+// WithMessage returns a copy of the error with its message replaced, the original error is unchanged
 func (h E) WithMessage(msg string) E {
 	return E{
 		Code:    h.Code,
 		Err:     h.Err,
 		Meta:    h.Meta,
+		LogMeta: h.LogMeta,
 		Message: msg,
 	}
 }
 
+// WithMessagef returns a copy of the error with its message replaced by a formatted string
+func (h E) WithMessagef(format string, values ...interface{}) E {
+	return h.WithMessage(fmt.Sprintf(format, values...))
+}
+
 func (h E) WithMeta(meta M) E {
 	return E{
 		Code:    h.Code,
 		Err:     h.Err,
 		Message: h.Message,
+		LogMeta: h.LogMeta,
 		Meta:    meta,
 	}
 }
 
+// WithLogMeta returns a copy of the error with a key/value added to its logging context.
+// LogMeta is attached to log entries when the error is logged, but never serialised into client responses.
+func (h E) WithLogMeta(key string, value interface{}) E {
+	logMeta := make(M, len(h.LogMeta)+1)
+	for k, v := range h.LogMeta {
+		logMeta[k] = v
+	}
+	logMeta[key] = value
+
+	return E{
+		Code:    h.Code,
+		Err:     h.Err,
+		Message: h.Message,
+		Meta:    h.Meta,
+		LogMeta: logMeta,
+	}
+}
+
+// WithCause returns a copy of the error with its underlying cause set.
+// The cause is logged for diagnosis, but never serialised into client responses.
+func (h E) WithCause(err error) E {
+	return E{
+		Code:    h.Code,
+		Err:     err,
+		Message: h.Message,
+		Meta:    h.Meta,
+		LogMeta: h.LogMeta,
+	}
+}
+
+// Unwrap returns the underlying cause, so the error works with errors.Is and errors.As
+func (h E) Unwrap() error {
+	return h.Err
+}
+
+// CauseDetail formats the full cause chain of the error for logging, or returns an empty string if it has no cause
+func (h E) CauseDetail() string {
+	if h.Err == nil {
+		return ""
+	}
+	return fmt.Sprintf("%+v", h.Err)
+}
+
 func New(code string) E {
 	return E{Code: code}
 }
@@ -0,0 +1,56 @@
+package hand
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWithMessagef(t *testing.T) {
+	err := New("rate_limited").WithMessagef("retry in %d seconds", 30)
+
+	if err.Message != "retry in 30 seconds" || err.Code != "rate_limited" {
+		t.Fatalf("unexpected error %#v", err)
+	}
+}
+
+func TestWithMessageCopies(t *testing.T) {
+	cause := errors.New("connection refused")
+	original := Wrap("unknown", cause).WithMessage("first").WithMeta(M{"attempt": 1}).WithLogMeta("host", "db")
+
+	replaced := original.WithMessage("second")
+
+	if original.Message != "first" {
+		t.Fatalf("expected the original message to be unchanged, got %q", original.Message)
+	}
+	if replaced.Message != "second" {
+		t.Fatalf("expected the message to be replaced, got %q", replaced.Message)
+	}
+	if replaced.Err != cause || replaced.Meta["attempt"] != 1 || replaced.LogMeta["host"] != "db" {
+		t.Fatalf("expected the cause and meta to be kept, got %#v", replaced)
+	}
+}
+
+func TestWithCause(t *testing.T) {
+	root := errors.New("connection refused")
+	err := New("unknown").WithMessage("lookup failed").WithCause(fmt.Errorf("query users: %w", root))
+
+	if !errors.Is(err, root) {
+		t.Fatal("expected the cause chain to be unwrappable")
+	}
+	if err.CauseDetail() != "query users: connection refused" {
+		t.Fatalf("expected the full cause chain, got %q", err.CauseDetail())
+	}
+	if err.Message != "lookup failed" {
+		t.Fatalf("expected the message to be kept, got %q", err.Message)
+	}
+	if New("unknown").CauseDetail() != "" {
+		t.Fatal("expected no cause detail without a cause")
+	}
+
+	b, _ := json.Marshal(err)
+	if string(b) != `{"code":"unknown","message":"lookup failed"}` {
+		t.Fatalf("expected the cause not to be serialised, got %s", b)
+	}
+}
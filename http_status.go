@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"net/http"
+)
+
+// HTTPStatus maps an error code to the HTTP status code used by the execution environments
+func HTTPStatus(code string) int {
+	switch code {
+	case ErrCodeBadRequest:
+		fallthrough
+	case ErrCodeInvalidBody:
+		fallthrough
+	case ErrCodeSchemaFailure:
+		fallthrough
+	case ErrCodeMissingBody:
+		return http.StatusBadRequest
+
+	case ErrCodeForbidden:
+		return http.StatusForbidden
+
+	case ErrCodeMethodNotFound:
+		fallthrough
+	case ErrCodeNotFound:
+		return http.StatusNotFound
+
+	case ErrCodeConflict:
+		return http.StatusConflict
+
+	case ErrCodeUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+
+	case ErrCodeRateLimited:
+		return http.StatusTooManyRequests
+
+	case ErrCodeNoAuthentication:
+		fallthrough
+	case ErrCodeInvalidAuthentication:
+		return http.StatusUnauthorized
+
+	case ErrCodeInsufficientTime:
+		return http.StatusServiceUnavailable
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
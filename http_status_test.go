@@ -0,0 +1,27 @@
+package runtime
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := map[string]int{
+		ErrCodeBadRequest:           http.StatusBadRequest,
+		ErrCodeSchemaFailure:        http.StatusBadRequest,
+		ErrCodeNoAuthentication:     http.StatusUnauthorized,
+		ErrCodeForbidden:            http.StatusForbidden,
+		ErrCodeMethodNotFound:       http.StatusNotFound,
+		ErrCodeConflict:             http.StatusConflict,
+		ErrCodeUnsupportedMediaType: http.StatusUnsupportedMediaType,
+		ErrCodeRateLimited:          http.StatusTooManyRequests,
+		ErrCodeUnknown:              http.StatusInternalServerError,
+		"custom_code":               http.StatusInternalServerError,
+	}
+
+	for code, expected := range tests {
+		if actual := HTTPStatus(code); actual != expected {
+			t.Errorf("%s: expected %d, got %d", code, expected, actual)
+		}
+	}
+}
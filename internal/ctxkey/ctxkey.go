@@ -0,0 +1,18 @@
+// Package ctxkey provides the context key type used by every runtime package.
+// Keys are pointers to an unexported struct so they can never collide with keys defined outside this module;
+// consumers must use the typed accessors exported by each package rather than reading context values directly.
+package ctxkey
+
+// Key is a context key
+type Key struct {
+	name string
+}
+
+// New creates a context key, the name is only used for debugging
+func New(name string) *Key {
+	return &Key{name: name}
+}
+
+func (k *Key) String() string {
+	return "runtime context key " + k.name
+}
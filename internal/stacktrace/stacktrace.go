@@ -0,0 +1,73 @@
+// Package stacktrace formats goroutine stack traces for panic logs.
+// Stacks are only ever logged, they must never be included in a response to a client.
+package stacktrace
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxDepth is the number of frames logged when no depth is configured
+const DefaultMaxDepth = 32
+
+// ignoredPrefixes are the packages whose frames are noise when reading a panic in application code
+var ignoredPrefixes = []string{
+	"runtime.",
+	"runtime/",
+	"reflect.",
+	"panic(",
+	"github.com/g-wilson/runtime.",
+	"github.com/g-wilson/runtime/",
+}
+
+// Format trims a stack from runtime/debug.Stack to the frames leading to the panic, dropping frames from the Go
+// runtime, reflection and this module, and keeping at most maxDepth frames. Every frame is kept if filtering would
+// leave none, so a panic within this module is still diagnosable.
+func Format(stack []byte, maxDepth int) string {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "goroutine ") {
+		lines = lines[1:]
+	}
+
+	// frames are a function line followed by a tab indented file line
+	var all, kept []string
+	for i := 0; i < len(lines); i += 2 {
+		frame := lines[i]
+		if i+1 < len(lines) {
+			frame += "\n" + lines[i+1]
+		}
+
+		all = append(all, frame)
+		if !isIgnored(lines[i]) {
+			kept = append(kept, frame)
+		}
+	}
+	if len(kept) == 0 {
+		kept = all
+	}
+
+	if len(kept) > maxDepth {
+		omitted := len(kept) - maxDepth
+		kept = append(kept[:maxDepth], fmt.Sprintf("...%d more frames", omitted))
+	}
+
+	return strings.Join(kept, "\n")
+}
+
+func isIgnored(function string) bool {
+	if strings.HasPrefix(function, "created by ") {
+		function = strings.TrimPrefix(function, "created by ")
+	}
+
+	for _, prefix := range ignoredPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
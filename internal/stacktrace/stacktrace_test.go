@@ -0,0 +1,64 @@
+package stacktrace
+
+import (
+	"strings"
+	"testing"
+)
+
+const testStack = `goroutine 7 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:24 +0x5e
+github.com/g-wilson/runtime/rpcservice.(*Method).call.func1()
+	/src/runtime/rpcservice/method.go:325 +0x8f
+panic({0x6b2f40, 0x8a1c30})
+	/usr/local/go/src/runtime/panic.go:884 +0x213
+example.com/app/users.lookup(...)
+	/src/app/users/lookup.go:12
+example.com/app/users.Get({0x8a5e28, 0xc0000a4000}, 0xc0000b2000)
+	/src/app/users/get.go:30 +0x45
+example.com/app/users.handler({0x8a5e28, 0xc0000a4000})
+	/src/app/users/handler.go:8 +0x25
+reflect.Value.call({0x6a8b20, 0x7d7a18, 0x13}, {0x75e4d1, 0x4}, {0xc0000b4000, 0x2, 0x2})
+	/usr/local/go/src/reflect/value.go:586 +0xb07
+github.com/g-wilson/runtime/rpcservice.(*Method).Invoke(0xc0000a2000, {0x8a5e28, 0xc0000a4000}, {0x0, 0x0, 0x0})
+	/src/runtime/rpcservice/method.go:230 +0x6c5
+created by net/http.(*Server).Serve
+	/usr/local/go/src/net/http/server.go:3089 +0x5ed`
+
+func TestFormatDropsRuntimeFrames(t *testing.T) {
+	formatted := Format([]byte(testStack), 0)
+
+	for _, dropped := range []string{"goroutine 7", "runtime/debug", "rpcservice", "panic(", "reflect."} {
+		if strings.Contains(formatted, dropped) {
+			t.Errorf("expected %q to be dropped, got:\n%s", dropped, formatted)
+		}
+	}
+
+	for _, kept := range []string{"users.lookup", "lookup.go:12", "users.Get", "users.handler", "created by net/http"} {
+		if !strings.Contains(formatted, kept) {
+			t.Errorf("expected %q to be kept, got:\n%s", kept, formatted)
+		}
+	}
+}
+
+func TestFormatLimitsDepth(t *testing.T) {
+	formatted := Format([]byte(testStack), 2)
+
+	lines := strings.Split(formatted, "\n")
+	if len(lines) != 5 || lines[4] != "...2 more frames" {
+		t.Fatalf("expected two frames and a count of the omitted frames, got:\n%s", formatted)
+	}
+	if !strings.HasPrefix(lines[0], "example.com/app/users.lookup") {
+		t.Fatalf("expected the frames nearest the panic to be kept, got:\n%s", formatted)
+	}
+}
+
+func TestFormatKeepsModuleFramesWhenNothingElseRemains(t *testing.T) {
+	stack := `goroutine 1 [running]:
+github.com/g-wilson/runtime/rpcservice.(*Method).call.func1()
+	/src/runtime/rpcservice/method.go:325 +0x8f`
+
+	if formatted := Format([]byte(stack), 0); !strings.Contains(formatted, "rpcservice.(*Method).call") {
+		t.Fatalf("expected module frames to be kept, got:\n%s", formatted)
+	}
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/internal/stacktrace"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Go runs fn in a new goroutine with a fresh context carrying the contextual logger, request ID and trace ID of ctx.
+// The fresh context is not cancelled when the request ends, and a panic in fn is recovered and logged with those fields.
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	bgCtx := context.Background()
+
+	if requestID, ok := runtime.RequestIDFromContext(ctx); ok {
+		bgCtx = runtime.WithRequestID(bgCtx, requestID)
+	}
+	if traceID, ok := runtime.TraceIDFromContext(ctx); ok {
+		bgCtx = runtime.WithTraceID(bgCtx, traceID)
+	}
+	if ctxLogger := FromContext(ctx); ctxLogger != nil {
+		bgCtx = SetContext(bgCtx, ctxLogger.Entry())
+	}
+
+	go func() {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+
+			entry := logrus.NewEntry(logrus.StandardLogger())
+			if ctxLogger := FromContext(bgCtx); ctxLogger != nil {
+				entry = ctxLogger.Entry()
+			}
+
+			entry.WithContext(bgCtx).WithFields(logrus.Fields{
+				"panic": fmt.Sprint(p),
+				"stack": stacktrace.Format(debug.Stack(), stacktrace.DefaultMaxDepth),
+			}).Error("goroutine panic recovered")
+		}()
+
+		fn(bgCtx)
+	}()
+}
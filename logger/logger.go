@@ -2,6 +2,12 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/g-wilson/runtime/internal/ctxkey"
 
 	"github.com/sirupsen/logrus"
 )
@@ -9,6 +15,8 @@ import (
 const (
 	// ServiceKey is the log field for the service name
 	ServiceKey = "svc"
+	// ServiceNameKey is the log field for the name of an RPC service within the process
+	ServiceNameKey = "service"
 	// LevelKey is the log field for the log level
 	LevelKey = "lvl"
 	// MessageKey is the log field for the log message
@@ -17,13 +25,22 @@ const (
 	TimestampKey = "t"
 )
 
-type ctxLoggerKey string
+const (
+	// EnvLogLevel is the environment variable read for the log level
+	EnvLogLevel = "LOG_LEVEL"
+	// EnvLogFormat is the environment variable read for the log format
+	EnvLogFormat = "LOG_FORMAT"
+	// EnvEnvironment is the environment variable read to determine whether the service is running in development
+	EnvEnvironment = "ENV"
+)
+
+var loggerKey = ctxkey.New("ctxlogger")
 
-var loggerKey = ctxLoggerKey("ctxlogger")
+var traceHookOnce sync.Once
 
 // Create creates a new Logrus Entry with defaults
 func Create(servicename, format, level string) *logrus.Entry {
-	logger := logrus.WithField("svc", servicename)
+	logger := logrus.WithField(ServiceKey, servicename)
 
 	switch format {
 	case "json":
@@ -42,9 +59,57 @@ func Create(servicename, format, level string) *logrus.Entry {
 	levelEnum, _ := logrus.ParseLevel(level)
 	logger.Logger.Level = levelEnum
 
+	traceHookOnce.Do(func() {
+		logger.Logger.AddHook(TraceHook{})
+	})
+
 	return logger
 }
 
+// CreateFromEnv creates a new Logrus Entry configured by the LOG_LEVEL and LOG_FORMAT environment variables.
+// When they are unset or invalid it falls back to debug/text in development (ENV=development) and info/json otherwise.
+func CreateFromEnv(servicename string) *logrus.Entry {
+	format, level := "json", "info"
+	if isDevelopment(os.Getenv(EnvEnvironment)) {
+		format, level = "text", "debug"
+	}
+
+	var warnings []string
+
+	if envFormat := strings.ToLower(os.Getenv(EnvLogFormat)); envFormat != "" {
+		if envFormat == "json" || envFormat == "text" {
+			format = envFormat
+		} else {
+			warnings = append(warnings, fmt.Sprintf("invalid %s %q, using %q", EnvLogFormat, envFormat, format))
+		}
+	}
+
+	if envLevel := os.Getenv(EnvLogLevel); envLevel != "" {
+		if _, err := logrus.ParseLevel(envLevel); err == nil {
+			level = envLevel
+		} else {
+			warnings = append(warnings, fmt.Sprintf("invalid %s %q, using %q", EnvLogLevel, envLevel, level))
+		}
+	}
+
+	log := Create(servicename, format, level)
+
+	for _, w := range warnings {
+		log.Warn(w)
+	}
+
+	return log
+}
+
+func isDevelopment(env string) bool {
+	switch strings.ToLower(env) {
+	case "development", "dev", "local":
+		return true
+	default:
+		return false
+	}
+}
+
 // ContextSafeLogger is an abstraction which allows the context to remain lightweight and hold just a pointer to a logger
 type ContextSafeLogger struct {
 	entry *logrus.Entry
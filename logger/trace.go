@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceIDKey is the log field for the OpenTelemetry trace ID
+	TraceIDKey = "trace_id"
+	// SpanIDKey is the log field for the OpenTelemetry span ID
+	SpanIDKey = "span_id"
+)
+
+// TraceHook is a Logrus hook which adds the active OpenTelemetry trace and span IDs to log entries
+// which carry a context. It does nothing when there is no valid span in the context.
+type TraceHook struct{}
+
+// Levels implements logrus.Hook
+func (TraceHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (TraceHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	sc := trace.SpanContextFromContext(entry.Context)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	entry.Data[TraceIDKey] = sc.TraceID().String()
+	entry.Data[SpanIDKey] = sc.SpanID().String()
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Page is the standard response envelope for list methods. Items must be a slice, NextCursor is empty on the last
+// page, and Total is only set when the count of all matching items is known.
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Total      *int        `json:"total,omitempty"`
+}
+
+// NewPage creates a Page of items, which are always encoded as an array even when the slice or items is nil.
+// It panics if items is not a slice or array, as that is a programming error rather than a request error.
+func NewPage(items interface{}, nextCursor string) *Page {
+	v := reflect.ValueOf(items)
+	switch {
+	case !v.IsValid():
+		items = []interface{}{}
+	case v.Kind() == reflect.Slice && v.IsNil():
+		items = reflect.MakeSlice(v.Type(), 0, 0).Interface()
+	case v.Kind() != reflect.Slice && v.Kind() != reflect.Array:
+		panic(fmt.Errorf("runtime page items must be a slice, %s provided", v.Type()))
+	}
+
+	return &Page{
+		Items:      items,
+		NextCursor: nextCursor,
+	}
+}
+
+// WithTotal sets the count of all items matching the request, across every page
+func (p *Page) WithTotal(total int) *Page {
+	p.Total = &total
+	return p
+}
+
+// HasMore reports whether there is another page to request with NextCursor
+func (p *Page) HasMore() bool {
+	return p.NextCursor != ""
+}
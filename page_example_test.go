@@ -0,0 +1,36 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/g-wilson/runtime"
+)
+
+type listUsersRequest struct {
+	Cursor string `json:"cursor"`
+}
+
+type user struct {
+	ID string `json:"id"`
+}
+
+// listUsers is a list method handler, which returns the envelope for the wrappers to encode
+func listUsers(ctx context.Context, req *listUsersRequest) (*runtime.Page, error) {
+	users := []user{{ID: "user_1"}, {ID: "user_2"}}
+
+	return runtime.NewPage(users, "user_2").WithTotal(5), nil
+}
+
+func ExampleNewPage() {
+	page, _ := listUsers(context.Background(), &listUsersRequest{})
+
+	b, _ := json.Marshal(page)
+	fmt.Println(string(b))
+	fmt.Println(page.HasMore())
+
+	// Output:
+	// {"items":[{"id":"user_1"},{"id":"user_2"}],"next_cursor":"user_2","total":5}
+	// true
+}
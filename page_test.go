@@ -0,0 +1,53 @@
+package runtime
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewPageEncodesItemsAsArray(t *testing.T) {
+	var nilSlice []string
+
+	tests := []struct {
+		name string
+		page *Page
+		body string
+		more bool
+	}{
+		{"nil items", NewPage(nil, ""), `{"items":[]}`, false},
+		{"nil slice", NewPage(nilSlice, ""), `{"items":[]}`, false},
+		{"items with cursor", NewPage([]string{"a"}, "next"), `{"items":["a"],"next_cursor":"next"}`, true},
+		{"total", NewPage([]string{"a"}, "").WithTotal(1), `{"items":["a"],"total":1}`, false},
+	}
+
+	for _, tc := range tests {
+		b, err := json.Marshal(tc.page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.body {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.body, b)
+		}
+		if tc.page.HasMore() != tc.more {
+			t.Errorf("%s: expected HasMore %t", tc.name, tc.more)
+		}
+	}
+}
+
+func TestNewPageRejectsNonSliceItems(t *testing.T) {
+	for _, items := range []interface{}{"a", map[string]string{"a": "b"}, struct{}{}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected %T items to be rejected", items)
+				}
+			}()
+
+			NewPage(items, "")
+		}()
+	}
+
+	if b, _ := json.Marshal(NewPage([2]string{"a", "b"}, "")); string(b) != `{"items":["a","b"]}` {
+		t.Errorf("expected arrays to be accepted, got %s", b)
+	}
+}
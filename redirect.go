@@ -0,0 +1,17 @@
+package runtime
+
+import "net/http"
+
+// Redirect can be returned by a handler to respond with a redirect to URL instead of a JSON body
+type Redirect struct {
+	URL    string
+	Status int
+}
+
+// RedirectStatus returns the status code of the redirect, defaulting to 302 Found when Status is not a 3xx code
+func (r *Redirect) RedirectStatus() int {
+	if r.Status < 300 || r.Status > 399 {
+		return http.StatusFound
+	}
+	return r.Status
+}
@@ -0,0 +1,23 @@
+package runtime
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRedirectStatus(t *testing.T) {
+	tests := map[int]int{
+		0:                            http.StatusFound,
+		http.StatusMovedPermanently:  http.StatusMovedPermanently,
+		http.StatusSeeOther:          http.StatusSeeOther,
+		http.StatusTemporaryRedirect: http.StatusTemporaryRedirect,
+		http.StatusOK:                http.StatusFound,
+		http.StatusBadRequest:        http.StatusFound,
+	}
+
+	for status, expected := range tests {
+		if actual := (&Redirect{URL: "/", Status: status}).RedirectStatus(); actual != expected {
+			t.Errorf("status %d: expected %d, got %d", status, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package runtime
+
+import (
+	"context"
+
+	"github.com/g-wilson/runtime/internal/ctxkey"
+)
+
+var requestIDKey = ctxkey.New("requestid")
+
+// WithRequestID adds the execution environment's request ID to a context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext retrieves the execution environment's request ID from the context
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
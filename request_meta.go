@@ -0,0 +1,39 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/g-wilson/runtime/internal/ctxkey"
+)
+
+var requestMetaKey = ctxkey.New("requestmeta")
+
+// RequestMeta holds transport details about the current request which handlers may need, such as for audit logging
+type RequestMeta struct {
+	SourceIP  string
+	UserAgent string
+	// Headers contains only the request headers allowlisted by the service, keyed by canonical header name
+	Headers map[string]string
+}
+
+// WithRequestMeta adds request metadata to a context
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey, meta)
+}
+
+// RequestMetaFromContext retrieves request metadata from the context
+func RequestMetaFromContext(ctx context.Context) (RequestMeta, bool) {
+	meta, ok := ctx.Value(requestMetaKey).(RequestMeta)
+	return meta, ok
+}
+
+// RequestHeader retrieves an allowlisted request header from the context, returning an empty string if it was not forwarded
+func RequestHeader(ctx context.Context, name string) string {
+	meta, ok := RequestMetaFromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return meta.Headers[http.CanonicalHeaderKey(name)]
+}
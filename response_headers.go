@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/g-wilson/runtime/internal/ctxkey"
+)
+
+var responseHeadersKey = ctxkey.New("responseheaders")
+
+type responseHeaders struct {
+	mu       sync.Mutex
+	header   http.Header
+	warnings []Warning
+}
+
+// Warning is a non-fatal issue with a successful request, such as use of a deprecated field or a partial result
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// WithResponseHeaders prepares a context so that handlers can set response headers on it
+func WithResponseHeaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, responseHeadersKey, &responseHeaders{header: http.Header{}})
+}
+
+// SetResponseHeader sets a header which will be merged into the HTTP response by the execution environment
+// It does nothing if the context was not prepared with WithResponseHeaders
+func SetResponseHeader(ctx context.Context, key, value string) {
+	rh, ok := ctx.Value(responseHeadersKey).(*responseHeaders)
+	if !ok {
+		return
+	}
+
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+
+	rh.header.Set(key, value)
+}
+
+// SetRetryAfter sets the Retry-After response header, rounded up to whole seconds
+func SetRetryAfter(ctx context.Context, d time.Duration) {
+	secs := int64((d + time.Second - 1) / time.Second)
+	SetResponseHeader(ctx, "Retry-After", strconv.FormatInt(secs, 10))
+}
+
+// AddWarning records a warning for the client and adds it to the response as a Warning header.
+// It does nothing if the context was not prepared with WithResponseHeaders
+func AddWarning(ctx context.Context, code, message string) {
+	rh, ok := ctx.Value(responseHeadersKey).(*responseHeaders)
+	if !ok {
+		return
+	}
+
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+
+	text := code
+	if message != "" {
+		text += ": " + message
+	}
+
+	rh.warnings = append(rh.warnings, Warning{Code: code, Message: message})
+	rh.header.Add("Warning", "299 - "+strconv.Quote(text))
+}
+
+// Warnings returns a copy of the warnings added to the context
+func Warnings(ctx context.Context) []Warning {
+	rh, ok := ctx.Value(responseHeadersKey).(*responseHeaders)
+	if !ok {
+		return nil
+	}
+
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+
+	return append([]Warning(nil), rh.warnings...)
+}
+
+// ResponseHeaders returns a copy of the response headers set on the context
+func ResponseHeaders(ctx context.Context) http.Header {
+	rh, ok := ctx.Value(responseHeadersKey).(*responseHeaders)
+	if !ok {
+		return http.Header{}
+	}
+
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+
+	return rh.header.Clone()
+}
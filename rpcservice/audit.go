@@ -0,0 +1,95 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/auth"
+	"github.com/g-wilson/runtime/logger"
+)
+
+// Auditor receives an entry for every successful invocation of an audited method. It is separate from the request
+// logger so the audit trail can be kept in an immutable store with its own retention.
+type Auditor interface {
+	Audit(ctx context.Context, entry AuditEntry)
+}
+
+// AuditEntry records who invoked a method which changes state, and what it changed
+type AuditEntry struct {
+	Time      time.Time   `json:"time"`
+	Service   string      `json:"service,omitempty"`
+	Method    string      `json:"method"`
+	Subject   string      `json:"subject,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	SourceIP  string      `json:"source_ip,omitempty"`
+	Detail    interface{} `json:"detail,omitempty"`
+}
+
+// WithAuditor sets the sink for audit entries of methods marked with WithAudit
+func (s *Service) WithAuditor(a Auditor) *Service {
+	s.Auditor = a
+	return s
+}
+
+// WithAudit records an audit entry each time the method succeeds. Handlers describe the change they made with
+// runtime.SetAuditDetail.
+func (m *Method) WithAudit() *Method {
+	return m.apply(func(m *Method) { m.Audited = true })
+}
+
+func (s *Service) audits(m *Method) bool {
+	return m.Audited && s.Auditor != nil
+}
+
+// audit sends the entry for a successful invocation to the auditor
+func (s *Service) audit(ctx context.Context, m *Method, startedAt time.Time) {
+	entry := AuditEntry{
+		Time:    startedAt.UTC(),
+		Service: s.Name,
+		Method:  m.Name,
+		Detail:  runtime.AuditDetail(ctx),
+	}
+	if claims, ok := auth.GetIdentityContext(ctx); ok {
+		entry.Subject = claims.Subject
+	}
+	if requestID, ok := runtime.RequestIDFromContext(ctx); ok {
+		entry.RequestID = requestID
+	}
+	if meta, ok := runtime.RequestMetaFromContext(ctx); ok {
+		entry.SourceIP = meta.SourceIP
+	}
+
+	s.Auditor.Audit(ctx, entry)
+}
+
+// JSONAuditor is an Auditor which writes each entry as a line of JSON
+type JSONAuditor struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONAuditor creates a JSONAuditor writing to w, or to stdout if w is nil
+func NewJSONAuditor(w io.Writer) *JSONAuditor {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	return &JSONAuditor{enc: json.NewEncoder(w)}
+}
+
+// Audit implements Auditor
+func (a *JSONAuditor) Audit(ctx context.Context, entry AuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.enc.Encode(entry); err != nil {
+		if reqLogger := logger.FromContext(ctx); reqLogger != nil {
+			reqLogger.Entry().WithError(err).Error("audit entry could not be written")
+		}
+	}
+}
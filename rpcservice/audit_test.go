@@ -0,0 +1,125 @@
+package rpcservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/auth"
+	"github.com/g-wilson/runtime/hand"
+)
+
+type recordingAuditor struct {
+	entries []AuditEntry
+}
+
+func (a *recordingAuditor) Audit(ctx context.Context, entry AuditEntry) {
+	a.entries = append(a.entries, entry)
+}
+
+func deleteUser(ctx context.Context) error {
+	runtime.SetAuditDetail(ctx, map[string]string{"user_id": "user_2"})
+	return nil
+}
+
+func auditContext() context.Context {
+	ctx := auth.SetIdentityContext(testContext(), auth.Claims{Subject: "user_1"})
+	ctx = runtime.WithRequestID(ctx, "req_1")
+	return runtime.WithRequestMeta(ctx, runtime.RequestMeta{SourceIP: "203.0.113.1"})
+}
+
+func TestAuditEntryRecorded(t *testing.T) {
+	auditor := &recordingAuditor{}
+	svc := NewService(testLogger()).
+		WithName("users").
+		WithAuditor(auditor).
+		AddMethod("deleteUser", deleteUser, nil)
+	method, _ := svc.GetMethod("deleteUser")
+	method.WithAudit()
+
+	if _, err := svc.Invoke(auditContext(), method, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(auditor.entries) != 1 {
+		t.Fatalf("expected one audit entry, got %d", len(auditor.entries))
+	}
+
+	entry := auditor.entries[0]
+	if entry.Service != "users" || entry.Method != "deleteUser" {
+		t.Errorf("unexpected service or method: %+v", entry)
+	}
+	if entry.Subject != "user_1" || entry.RequestID != "req_1" || entry.SourceIP != "203.0.113.1" {
+		t.Errorf("unexpected caller details: %+v", entry)
+	}
+	if detail, ok := entry.Detail.(map[string]string); !ok || detail["user_id"] != "user_2" {
+		t.Errorf("expected the handler's detail, got %#v", entry.Detail)
+	}
+	if entry.Time.IsZero() {
+		t.Error("expected the entry to be timestamped")
+	}
+}
+
+func TestAuditSkipsFailuresAndUnauditedMethods(t *testing.T) {
+	auditor := &recordingAuditor{}
+	svc := NewService(testLogger()).
+		WithAuditor(auditor).
+		AddMethod("failing", func(ctx context.Context) error {
+			runtime.SetAuditDetail(ctx, "partial")
+			return hand.New(runtime.ErrCodeForbidden)
+		}, nil).
+		AddMethod("read", noop, nil)
+
+	failing, _ := svc.GetMethod("failing")
+	failing.WithAudit()
+	read, _ := svc.GetMethod("read")
+
+	if _, err := svc.Invoke(auditContext(), failing, nil); err == nil {
+		t.Fatal("expected the failing method to return an error")
+	}
+	if _, err := svc.Invoke(auditContext(), read, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(auditor.entries) != 0 {
+		t.Fatalf("expected no audit entries, got %+v", auditor.entries)
+	}
+}
+
+func TestSetAuditDetailWithoutAudit(t *testing.T) {
+	ctx := context.Background()
+	runtime.SetAuditDetail(ctx, "ignored")
+
+	if detail := runtime.AuditDetail(ctx); detail != nil {
+		t.Fatalf("expected no detail outside an audited invocation, got %v", detail)
+	}
+}
+
+func TestJSONAuditor(t *testing.T) {
+	var buf bytes.Buffer
+	auditor := NewJSONAuditor(&buf)
+
+	auditor.Audit(testContext(), AuditEntry{Method: "deleteUser", Subject: "user_1"})
+	auditor.Audit(testContext(), AuditEntry{Method: "deleteGroup"})
+
+	dec := json.NewDecoder(&buf)
+	var methods []string
+	for {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatal(err)
+			}
+			break
+		}
+		methods = append(methods, entry["method"].(string))
+	}
+
+	if len(methods) != 2 || methods[0] != "deleteUser" || methods[1] != "deleteGroup" {
+		t.Fatalf("expected one JSON line per entry, got %v", methods)
+	}
+}
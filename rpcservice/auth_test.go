@@ -0,0 +1,120 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func identityProvider(ctx context.Context, claims map[string]interface{}) context.Context {
+	return ctx
+}
+
+func httpEvent(method string, claims map[string]string) events.APIGatewayV2HTTPRequest {
+	event := events.APIGatewayV2HTTPRequest{PathParameters: map[string]string{"method": method}}
+	event.RequestContext.Authorizer.JWT.Claims = claims
+
+	return event
+}
+
+func websocketEvent(route string, authorizer interface{}) events.APIGatewayWebsocketProxyRequest {
+	event := events.APIGatewayWebsocketProxyRequest{}
+	event.RequestContext.RouteKey = route
+	event.RequestContext.Authorizer = authorizer
+
+	return event
+}
+
+func TestFailClosed(t *testing.T) {
+	tests := []struct {
+		name   string
+		svc    *Service
+		claims map[string]string
+		status int
+	}{
+		{
+			name:   "identity provider without authorizer",
+			svc:    NewService(testLogger()).WithFailClosed().WithIdentityProvider(identityProvider),
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "identity provider with authorizer",
+			svc:    NewService(testLogger()).WithFailClosed().WithIdentityProvider(identityProvider),
+			claims: map[string]string{"sub": "user_1"},
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "tenant extractor without authorizer",
+			svc:    NewService(testLogger()).WithFailClosed().WithTenantExtractor(TenantClaim("tenant")),
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "no identity used",
+			svc:    NewService(testLogger()).WithFailClosed(),
+			status: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.svc.AddMethod("noop", noop, nil)
+
+			res, err := tt.svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("noop", tt.claims))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode, res.Body)
+			}
+		})
+	}
+}
+
+func TestFailClosedAllowsAnonymousMethods(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithFailClosed().
+		WithIdentityProvider(identityProvider).
+		AddMethod("noop", noop, nil)
+
+	method, _ := svc.GetMethod("noop")
+	method.WithAllowAnonymous()
+
+	res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("noop", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected anonymous method to be served, got %d: %s", res.StatusCode, res.Body)
+	}
+}
+
+func TestWebsocketFailClosed(t *testing.T) {
+	invoked := 0
+	svc := NewService(testLogger()).
+		WithFailClosed().
+		WithIdentityProvider(identityProvider).
+		AddMethod("$connect", func(ctx context.Context) error {
+			invoked++
+			return nil
+		}, nil)
+
+	handler := svc.WrapAPIGatewayWebsocket()
+
+	res, err := handler(context.Background(), websocketEvent("$connect", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusUnauthorized || invoked != 0 {
+		t.Fatalf("expected connection without authorizer context to be rejected, got %d", res.StatusCode)
+	}
+
+	res, err = handler(context.Background(), websocketEvent("$connect", map[string]interface{}{"principalId": "user_1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || invoked != 1 {
+		t.Fatalf("expected connection with authorizer context to be accepted, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,8 @@
+package rpcservice
+
+// BinaryResponse can be returned by a handler to respond with raw bytes instead of a JSON body
+// func(ctx context.Context) (response *rpcservice.BinaryResponse, err error)
+type BinaryResponse struct {
+	ContentType string
+	Body        []byte
+}
@@ -0,0 +1,56 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+var pdf = []byte("%PDF-1.4\x00\xff")
+
+func renderPDF(ctx context.Context) (*BinaryResponse, error) {
+	return &BinaryResponse{ContentType: "application/pdf", Body: pdf}, nil
+}
+
+func TestBinaryResponse(t *testing.T) {
+	svc := NewService(testLogger()).AddMethod("render", renderPDF, nil)
+
+	res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("render", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != http.StatusOK || res.Headers["Content-Type"] != "application/pdf" || !res.IsBase64Encoded {
+		t.Fatalf("expected a base64 encoded pdf response, got %d %v base64=%t", res.StatusCode, res.Headers, res.IsBase64Encoded)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != string(pdf) {
+		t.Fatalf("expected the raw bytes, got %q", body)
+	}
+}
+
+func TestNilBinaryResponse(t *testing.T) {
+	svc := NewService(testLogger()).
+		AddMethod("render", func(ctx context.Context) (*BinaryResponse, error) {
+			return nil, nil
+		}, nil).
+		SetFallback(func(ctx context.Context, methodName string, body []byte) (interface{}, error) {
+			var bin *BinaryResponse
+			return bin, nil
+		})
+
+	for _, method := range []string{"render", "unregistered"} {
+		res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent(method, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusNoContent || res.Body != "" {
+			t.Fatalf("%s: expected no content, got %d: %s", method, res.StatusCode, res.Body)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package rpcservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestErrorCauseLoggedNotReturned(t *testing.T) {
+	log, hook := test.NewNullLogger()
+
+	svc := NewService(logrus.NewEntry(log)).
+		WithEnvironment(EnvironmentDevelopment).
+		AddMethod("lookup", func(ctx context.Context) error {
+			cause := fmt.Errorf("query users: %w", errors.New("connection refused"))
+			return hand.New(runtime.ErrCodeUnknown).WithMessage("lookup failed").WithCause(cause)
+		}, nil)
+
+	res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("lookup", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(res.Body, "lookup failed") || strings.Contains(res.Body, "connection refused") {
+		t.Fatalf("expected the message without the cause, got %s", res.Body)
+	}
+
+	var logged bool
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["err_cause"] == "query users: connection refused" {
+			logged = true
+		}
+	}
+	if !logged {
+		t.Fatal("expected the cause chain to be logged")
+	}
+}
@@ -0,0 +1,40 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+)
+
+type appConfig struct {
+	Greeting string
+}
+
+type greetingResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+func greet(ctx context.Context) (*greetingResponse, error) {
+	cfg, ok := runtime.ConfigFromContext(ctx)
+	if !ok {
+		return &greetingResponse{}, nil
+	}
+
+	return &greetingResponse{Greeting: cfg.(*appConfig).Greeting}, nil
+}
+
+func TestWithConfig(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithConfig(&appConfig{Greeting: "hello"}).
+		AddMethod("greet", greet, nil)
+
+	res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("greet", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || res.Body != `{"greeting":"hello"}` {
+		t.Fatalf("expected the handler to read the service config, got %d: %s", res.StatusCode, res.Body)
+	}
+}
@@ -0,0 +1,110 @@
+package rpcservice
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// DraftRouter is a Validator which compiles each schema with the validator registered for the draft declared by its
+// $schema keyword, so services can mix schemas written for different drafts. Schemas without a $schema keyword, or
+// declaring a draft with no registered validator, are compiled with the Default validator.
+type DraftRouter struct {
+	Default Validator
+	Drafts  map[string]Validator
+}
+
+type draftSchema struct {
+	validator Validator
+	compiled  CompiledSchema
+}
+
+// NewDraftRouter creates a DraftRouter which falls back to the provided validator
+func NewDraftRouter(fallback Validator) *DraftRouter {
+	return &DraftRouter{
+		Default: fallback,
+		Drafts:  map[string]Validator{},
+	}
+}
+
+// WithDraft registers the validator used for schemas declaring the $schema URI
+func (r *DraftRouter) WithDraft(uri string, v Validator) *DraftRouter {
+	r.Drafts[normalizeDraftURI(uri)] = v
+	return r
+}
+
+// Compile implements Validator
+func (r *DraftRouter) Compile(schema gojsonschema.JSONLoader) (CompiledSchema, error) {
+	return r.CompileWithRefs(schema, nil)
+}
+
+// CompileWithRefs implements RefResolver
+func (r *DraftRouter) CompileWithRefs(schema gojsonschema.JSONLoader, shared map[string]gojsonschema.JSONLoader) (CompiledSchema, error) {
+	v, err := r.validatorFor(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var compiled CompiledSchema
+	if len(shared) == 0 {
+		compiled, err = v.Compile(schema)
+	} else if resolver, ok := v.(RefResolver); ok {
+		compiled, err = resolver.CompileWithRefs(schema, shared)
+	} else {
+		err = fmt.Errorf("validator %T cannot resolve shared schemas", v)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return draftSchema{validator: v, compiled: compiled}, nil
+}
+
+// Validate implements Validator
+func (r *DraftRouter) Validate(compiled CompiledSchema, data []byte) error {
+	ds, ok := compiled.(draftSchema)
+	if !ok {
+		return fmt.Errorf("draft router cannot use schema of type %T", compiled)
+	}
+
+	return ds.validator.Validate(ds.compiled, data)
+}
+
+func (r *DraftRouter) validatorFor(schema gojsonschema.JSONLoader) (Validator, error) {
+	uri, err := SchemaDraft(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := r.Drafts[normalizeDraftURI(uri)]; ok {
+		return v, nil
+	}
+	if r.Default == nil {
+		return GoJSONSchemaValidator{}, nil
+	}
+
+	return r.Default, nil
+}
+
+// SchemaDraft returns the $schema URI declared by a schema, or an empty string if it does not declare one
+func SchemaDraft(schema gojsonschema.JSONLoader) (string, error) {
+	doc, err := schema.LoadJSON()
+	if err != nil {
+		return "", err
+	}
+
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+
+	uri, _ := obj["$schema"].(string)
+	return uri, nil
+}
+
+// normalizeDraftURI ignores the empty fragment and scheme differences which draft URIs are commonly written with
+func normalizeDraftURI(uri string) string {
+	uri = strings.TrimSuffix(strings.TrimSpace(uri), "#")
+	return strings.TrimPrefix(strings.TrimPrefix(uri, "https://"), "http://")
+}
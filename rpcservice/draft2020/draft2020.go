@@ -0,0 +1,234 @@
+// Package draft2020 provides an rpcservice.Validator backed by github.com/santhosh-tekuri/jsonschema,
+// which supports JSON Schema drafts up to 2020-12
+package draft2020
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const schemaURL = "runtime://method-schema.json"
+
+// SchemaURI is the $schema URI of draft 2020-12, for registering this validator with rpcservice.DraftRouter
+const SchemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// Validator implements rpcservice.Validator
+type Validator struct{}
+
+// New creates a draft 2020-12 capable validator. Schemas which declare an earlier draft with $schema are compiled
+// according to that draft.
+func New() Validator {
+	return Validator{}
+}
+
+// Compile implements rpcservice.Validator
+func (v Validator) Compile(schema gojsonschema.JSONLoader) (rpcservice.CompiledSchema, error) {
+	return v.CompileWithRefs(schema, nil)
+}
+
+// compiledSchema keeps the schema documents alongside the compiled schema, so violations can report the keyword values
+type compiledSchema struct {
+	schema *jsonschema.Schema
+	docs   map[string]interface{}
+}
+
+// CompileWithRefs implements rpcservice.RefResolver
+func (Validator) CompileWithRefs(schema gojsonschema.JSONLoader, shared map[string]gojsonschema.JSONLoader) (rpcservice.CompiledSchema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+
+	compiled := &compiledSchema{docs: map[string]interface{}{}}
+
+	for url, loader := range shared {
+		if err := compiled.addResource(compiler, url, loader); err != nil {
+			return nil, fmt.Errorf("shared schema %s: %w", url, err)
+		}
+	}
+
+	if err := compiled.addResource(compiler, schemaURL, schema); err != nil {
+		return nil, err
+	}
+
+	sc, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, err
+	}
+	compiled.schema = sc
+
+	return compiled, nil
+}
+
+func (c *compiledSchema) addResource(compiler *jsonschema.Compiler, url string, schema gojsonschema.JSONLoader) error {
+	doc, err := schema.LoadJSON()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	c.docs[url] = doc
+
+	return compiler.AddResource(url, bytes.NewReader(b))
+}
+
+// Validate implements rpcservice.Validator
+func (Validator) Validate(compiled rpcservice.CompiledSchema, data []byte) error {
+	sc, ok := compiled.(*compiledSchema)
+	if !ok {
+		return fmt.Errorf("draft2020 validator cannot use schema of type %T", compiled)
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+
+	err := sc.schema.Validate(doc)
+	if err == nil {
+		return nil
+	}
+
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+
+	schemaErr := &rpcservice.SchemaError{}
+	for _, cause := range leafErrors(validationErr) {
+		keyword := keywordName(cause.KeywordLocation)
+		params := map[string]interface{}{}
+		if value, ok := sc.keywordValue(cause.AbsoluteKeywordLocation); ok {
+			params[keyword] = value
+		}
+
+		reason := rpcservice.SchemaErrorReason{
+			Field:   fieldName(cause.InstanceLocation),
+			Type:    keyword,
+			Message: cause.Message,
+			Keyword: keyword,
+			Params:  params,
+		}
+
+		switch keyword {
+		case "required", "additionalProperties":
+			// one reason per property, matching the default validator
+			for _, property := range quotedArgs(cause.Message) {
+				perProperty := reason
+				perProperty.Params = map[string]interface{}{"property": property}
+				for key, val := range params {
+					perProperty.Params[key] = val
+				}
+				schemaErr.Reasons = append(schemaErr.Reasons, perProperty)
+			}
+			continue
+
+		case "type":
+			if match := givenTypePattern.FindStringSubmatch(cause.Message); match != nil {
+				params["given"] = match[1]
+			}
+		}
+
+		schemaErr.Reasons = append(schemaErr.Reasons, reason)
+	}
+
+	return schemaErr
+}
+
+// keywordValue resolves an absolute keyword location, such as runtime://method-schema.json#/properties/name/minLength,
+// to the value of that keyword in the schema document
+func (c *compiledSchema) keywordValue(location string) (interface{}, bool) {
+	hash := strings.Index(location, "#")
+	if hash == -1 {
+		return nil, false
+	}
+
+	value, ok := c.docs[location[:hash]]
+	if !ok {
+		return nil, false
+	}
+
+	pointer := strings.TrimPrefix(location[hash+1:], "/")
+	if pointer == "" {
+		return value, true
+	}
+
+	for _, token := range strings.Split(pointer, "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if value, ok = v[token]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+var (
+	quotedArgPattern = regexp.MustCompile(`'((?:[^'\\]|\\.)*)'`)
+	givenTypePattern = regexp.MustCompile(`but got (\w+)$`)
+)
+
+// quotedArgs extracts the property names which jsonschema quotes into its messages
+func quotedArgs(message string) []string {
+	var args []string
+	for _, match := range quotedArgPattern.FindAllStringSubmatch(message, -1) {
+		arg := strings.ReplaceAll(match[1], `\'`, `'`)
+		if unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`); err == nil {
+			arg = unquoted
+		}
+		args = append(args, arg)
+	}
+
+	return args
+}
+
+// leafErrors flattens the error tree into the errors which describe individual violations
+func leafErrors(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+
+	return leaves
+}
+
+// fieldName converts a JSON pointer into the dotted field format used by the default validator
+func fieldName(pointer string) string {
+	if pointer == "" {
+		return "(root)"
+	}
+
+	return strings.ReplaceAll(strings.TrimPrefix(pointer, "/"), "/", ".")
+}
+
+func keywordName(location string) string {
+	return location[strings.LastIndex(location, "/")+1:]
+}
@@ -0,0 +1,109 @@
+package draft2020
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const testSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["name", "email"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 3},
+		"code": {"type": "string", "pattern": "^[A-Z]+$"},
+		"age": {"type": "integer"}
+	}
+}`
+
+func validate(t *testing.T, data string) map[string]rpcservice.SchemaErrorReason {
+	v := New()
+
+	compiled, err := v.Compile(gojsonschema.NewStringLoader(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = v.Validate(compiled, []byte(data))
+	schemaErr, ok := err.(*rpcservice.SchemaError)
+	if !ok {
+		t.Fatalf("expected schema error, got %v", err)
+	}
+
+	reasons := map[string]rpcservice.SchemaErrorReason{}
+	for _, reason := range schemaErr.Reasons {
+		key := reason.Keyword
+		if property, ok := reason.Params["property"].(string); ok {
+			key += ":" + property
+		} else {
+			key += ":" + reason.Field
+		}
+		reasons[key] = reason
+	}
+
+	return reasons
+}
+
+func TestValidateParams(t *testing.T) {
+	reasons := validate(t, `{"name": "ab", "code": "abc", "age": "old", "extra": true}`)
+
+	tests := []struct {
+		key    string
+		field  string
+		params map[string]interface{}
+	}{
+		{"minLength:name", "name", map[string]interface{}{"minLength": json.Number("3")}},
+		{"pattern:code", "code", map[string]interface{}{"pattern": "^[A-Z]+$"}},
+		{"type:age", "age", map[string]interface{}{"type": "integer", "given": "string"}},
+		{"required:email", "(root)", map[string]interface{}{"required": []interface{}{"name", "email"}, "property": "email"}},
+		{"additionalProperties:extra", "(root)", map[string]interface{}{"additionalProperties": false, "property": "extra"}},
+	}
+
+	for _, tc := range tests {
+		reason, ok := reasons[tc.key]
+		if !ok {
+			t.Errorf("expected a %s violation, got %v", tc.key, reasons)
+			continue
+		}
+
+		if reason.Field != tc.field {
+			t.Errorf("%s: expected field %q, got %q", tc.key, tc.field, reason.Field)
+		}
+
+		expected, _ := json.Marshal(tc.params)
+		actual, _ := json.Marshal(reason.Params)
+		if string(expected) != string(actual) {
+			t.Errorf("%s: expected params %s, got %s", tc.key, expected, actual)
+		}
+	}
+}
+
+func TestValidateParamsThroughSharedRef(t *testing.T) {
+	v := New()
+
+	shared := map[string]gojsonschema.JSONLoader{
+		"runtime://shared/name.json": gojsonschema.NewStringLoader(`{"type": "string", "maxLength": 2}`),
+	}
+	schema := gojsonschema.NewStringLoader(`{"type": "object", "properties": {"name": {"$ref": "runtime://shared/name.json"}}}`)
+
+	compiled, err := v.CompileWithRefs(schema, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = v.Validate(compiled, []byte(`{"name": "abc"}`))
+	schemaErr, ok := err.(*rpcservice.SchemaError)
+	if !ok || len(schemaErr.Reasons) != 1 {
+		t.Fatalf("expected one schema violation, got %v", err)
+	}
+
+	reason := schemaErr.Reasons[0]
+	if reason.Keyword != "maxLength" || reason.Params["maxLength"] != json.Number("2") {
+		t.Fatalf("expected maxLength violation with its limit, got %+v", reason)
+	}
+}
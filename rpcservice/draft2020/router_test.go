@@ -0,0 +1,41 @@
+package draft2020
+
+import (
+	"testing"
+
+	"github.com/g-wilson/runtime/rpcservice"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestDraftRouterSelectsValidatorByDraft(t *testing.T) {
+	router := rpcservice.NewDraftRouter(rpcservice.GoJSONSchemaValidator{}).WithDraft(SchemaURI, New())
+
+	// prefixItems was introduced in draft 2020-12, earlier drafts ignore it
+	tests := []struct {
+		name   string
+		schema string
+		valid  bool
+	}{
+		{"draft 2020-12", `{"$schema": "https://json-schema.org/draft/2020-12/schema#", "prefixItems": [{"type": "string"}]}`, false},
+		{"draft-07", `{"$schema": "http://json-schema.org/draft-07/schema#", "prefixItems": [{"type": "string"}]}`, true},
+		{"undeclared", `{"prefixItems": [{"type": "string"}]}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := router.Compile(gojsonschema.NewStringLoader(tt.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = router.Validate(compiled, []byte(`[1]`))
+			if tt.valid && err != nil {
+				t.Fatalf("expected [1] to be valid, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected [1] to be invalid")
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package rpcservice
+
+import (
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestSchemaDraft(t *testing.T) {
+	tests := map[string]string{
+		`{"$schema": "http://json-schema.org/draft-07/schema#", "type": "object"}`: "http://json-schema.org/draft-07/schema#",
+		`{"type": "object"}`: "",
+		`true`:               "",
+	}
+
+	for schema, expected := range tests {
+		uri, err := SchemaDraft(gojsonschema.NewStringLoader(schema))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uri != expected {
+			t.Errorf("%s: expected %q, got %q", schema, expected, uri)
+		}
+	}
+}
+
+func TestNormalizeDraftURI(t *testing.T) {
+	for _, uri := range []string{
+		"http://json-schema.org/draft-07/schema#",
+		"https://json-schema.org/draft-07/schema",
+		" http://json-schema.org/draft-07/schema ",
+	} {
+		if normalized := normalizeDraftURI(uri); normalized != "json-schema.org/draft-07/schema" {
+			t.Errorf("%q: got %q", uri, normalized)
+		}
+	}
+}
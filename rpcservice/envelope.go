@@ -0,0 +1,42 @@
+package rpcservice
+
+import (
+	"context"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+)
+
+// envelope gives every response the same shape when the service enables it
+type envelope struct {
+	Data     interface{}       `json:"data,omitempty"`
+	Error    *hand.E           `json:"error,omitempty"`
+	Warnings []runtime.Warning `json:"warnings,omitempty"`
+}
+
+// WithEnvelope wraps successful results as {"data": ...} and errors as {"error": ...} in every execution environment,
+// along with any warnings added to the request. Without it, results and errors are returned bare.
+// JSON-RPC responses keep the JSON-RPC 2.0 shape, which already separates result and error, and the devserver's
+// not_found response for paths outside every service has no service settings to apply, so neither is enveloped.
+func (s *Service) WithEnvelope() *Service {
+	s.EnableEnvelope = true
+	return s
+}
+
+// EnvelopeResult returns the value to encode as the body of a successful response
+func (s *Service) EnvelopeResult(ctx context.Context, result interface{}) interface{} {
+	if !s.EnableEnvelope {
+		return result
+	}
+
+	return envelope{Data: result, Warnings: runtime.Warnings(ctx)}
+}
+
+// EnvelopeError returns the value to encode as the body of an error response
+func (s *Service) EnvelopeError(handErr hand.E) interface{} {
+	if !s.EnableEnvelope {
+		return handErr
+	}
+
+	return envelope{Error: &handErr}
+}
@@ -0,0 +1,38 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+)
+
+func taggedList(ctx context.Context) (*listResponse, error) {
+	runtime.SetETag(ctx, "v1")
+	return listItems(ctx)
+}
+
+func TestETag(t *testing.T) {
+	svc := NewService(testLogger()).AddMethod("list", taggedList, nil)
+	handler := svc.WrapAPIGatewayHTTP()
+
+	res, err := handler(context.Background(), httpEvent("list", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || res.Headers["Etag"] != `"v1"` {
+		t.Fatalf("expected the response to carry its ETag, got %d %v", res.StatusCode, res.Headers)
+	}
+
+	event := httpEvent("list", nil)
+	event.Headers = map[string]string{"if-none-match": `"v1"`}
+
+	res, err = handler(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusNotModified || res.Body != "" {
+		t.Fatalf("expected 304 without a body, got %d: %s", res.StatusCode, res.Body)
+	}
+}
@@ -0,0 +1,62 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+)
+
+type proxiedResponse struct {
+	Method string `json:"method"`
+	Body   string `json:"body"`
+}
+
+func legacyProxy(ctx context.Context, methodName string, body []byte) (interface{}, error) {
+	if methodName == "removed" {
+		return nil, hand.New(runtime.ErrCodeNotFound).WithMessage("method was removed")
+	}
+
+	return &proxiedResponse{Method: methodName, Body: string(body)}, nil
+}
+
+func TestFallback(t *testing.T) {
+	svc := NewService(testLogger()).AddMethod("noop", noop, nil).SetFallback(legacyProxy)
+	handler := svc.WrapAPIGatewayHTTP()
+
+	event := httpEvent("legacyMethod", nil)
+	event.Body = `not even json`
+
+	res, err := handler(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || res.Body != `{"method":"legacyMethod","body":"not even json"}` {
+		t.Fatalf("expected the fallback to receive the method name and raw body, got %d: %s", res.StatusCode, res.Body)
+	}
+
+	res, err = handler(context.Background(), httpEvent("removed", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusNotFound || !strings.Contains(res.Body, "method was removed") {
+		t.Fatalf("expected the fallback error, got %d: %s", res.StatusCode, res.Body)
+	}
+
+	res, err = handler(context.Background(), httpEvent("noop", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected registered methods to bypass the fallback, got %d: %s", res.StatusCode, res.Body)
+	}
+}
+
+func TestWithoutFallback(t *testing.T) {
+	if _, ok := NewService(testLogger()).GetMethod("missing"); ok {
+		t.Fatal("expected unregistered methods not to be found without a fallback")
+	}
+}
@@ -0,0 +1,38 @@
+package rpcservice
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+)
+
+// DecompressRequestBody returns the body decompressed when the Content-Encoding is gzip, otherwise the body unchanged.
+// Decompression stops once the body exceeds the service's request size limit, so compressed bombs are rejected.
+func (s *Service) DecompressRequestBody(contentEncoding string, body []byte) ([]byte, error) {
+	if len(body) == 0 || !strings.EqualFold(strings.TrimSpace(contentEncoding), "gzip") {
+		return body, nil
+	}
+
+	maxBytes := s.MaxRequestBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxRequestBytes
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, hand.New(runtime.ErrCodeInvalidBody).WithMessage("gzip decoding error")
+	}
+	defer gz.Close()
+
+	decompressed, err := ioutil.ReadAll(io.LimitReader(gz, int64(maxBytes)+1))
+	if err != nil {
+		return nil, hand.New(runtime.ErrCodeInvalidBody).WithMessage("gzip decoding error")
+	}
+
+	return decompressed, checkRequestSize(decompressed, maxBytes)
+}
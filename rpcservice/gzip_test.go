@@ -0,0 +1,77 @@
+package rpcservice
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecompressRequestBody(t *testing.T) {
+	svc := NewService(testLogger()).WithMaxRequestBytes(1000)
+	compressed := gzipBytes(t, echoJSON)
+
+	body, err := svc.DecompressRequestBody("gzip", compressed)
+	if err != nil || !bytes.Equal(body, echoJSON) {
+		t.Fatalf("expected the body to be decompressed, got %q: %v", body, err)
+	}
+
+	body, err = svc.DecompressRequestBody("", echoJSON)
+	if err != nil || !bytes.Equal(body, echoJSON) {
+		t.Fatalf("expected an uncompressed body to be unchanged, got %q: %v", body, err)
+	}
+
+	if _, err := svc.DecompressRequestBody("gzip", echoJSON); err == nil {
+		t.Fatal("expected a body which is not gzip to be rejected")
+	}
+
+	bomb := gzipBytes(t, bytes.Repeat([]byte(" "), 100000))
+	if _, err := svc.DecompressRequestBody("gzip", bomb); err == nil {
+		t.Fatal("expected a body decompressing beyond the size limit to be rejected")
+	}
+}
+
+func TestGzipRequestThroughAPIGateway(t *testing.T) {
+	svc := NewService(testLogger()).AddMethod("echo", echo, echoSchema)
+
+	event := httpEvent("echo", nil)
+	event.Headers = map[string]string{"content-encoding": "gzip"}
+	event.Body = base64.StdEncoding.EncodeToString(gzipBytes(t, echoJSON))
+	event.IsBase64Encoded = true
+
+	res, err := svc.WrapAPIGatewayHTTP()(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || !strings.Contains(res.Body, `"name":"benchmark"`) {
+		t.Fatalf("expected the decompressed body to be echoed, got %d: %s", res.StatusCode, res.Body)
+	}
+
+	event.Body = base64.StdEncoding.EncodeToString(echoJSON)
+	res, err = svc.WrapAPIGatewayHTTP()(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(res.Body, runtime.ErrCodeInvalidBody) {
+		t.Fatalf("expected a corrupt gzip body to be rejected, got %d: %s", res.StatusCode, res.Body)
+	}
+}
@@ -0,0 +1,250 @@
+package rpcservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	JSONRPCServerError    = -32000
+)
+
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// MarshalJSON includes the result of every successful response, even when it is null, and omits it from errors,
+// as a JSON-RPC 2.0 response must have exactly one of result or error
+func (r jsonRPCResponse) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Error   *jsonRPCError   `json:"error"`
+			ID      json.RawMessage `json:"id"`
+		}{r.JSONRPC, r.Error, r.ID})
+	}
+
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  interface{}     `json:"result"`
+		ID      json.RawMessage `json:"id"`
+	}{r.JSONRPC, r.Result, r.ID})
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    hand.E `json:"data"`
+}
+
+// HandleJSONRPC dispatches a JSON-RPC 2.0 request or batch to the service's methods and returns the encoded response.
+// The context must already carry the logger and identity from the execution environment; context providers are run
+// here. A nil response means the
+// request contained only notifications and nothing should be written. Unauthenticated requests are rejected for
+// methods which are not anonymous when the service requires authentication.
+func (s *Service) HandleJSONRPC(ctx context.Context, body []byte, authenticated bool) []byte {
+	ctx, err := s.ProvideContext(ctx)
+	if err != nil {
+		return s.JSONRPCErrorResponse(body, err)
+	}
+
+	trimmed := bytes.TrimSpace(body)
+
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			return encodeJSONRPC(jsonRPCErrorResponse(nil, JSONRPCParseError, hand.New(runtime.ErrCodeInvalidBody)))
+		}
+		if len(batch) == 0 {
+			return encodeJSONRPC(jsonRPCErrorResponse(nil, JSONRPCInvalidRequest, hand.New(runtime.ErrCodeBadRequest)))
+		}
+
+		responses := []*jsonRPCResponse{}
+		for _, raw := range batch {
+			if res := s.handleJSONRPCRequest(ctx, raw, authenticated); res != nil {
+				responses = append(responses, res)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+
+		return encodeJSONRPC(responses)
+	}
+
+	res := s.handleJSONRPCRequest(ctx, trimmed, authenticated)
+	if res == nil {
+		return nil
+	}
+
+	return encodeJSONRPC(res)
+}
+
+func (s *Service) handleJSONRPCRequest(ctx context.Context, raw []byte, authenticated bool) *jsonRPCResponse {
+	var req jsonRPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return jsonRPCErrorResponse(nil, JSONRPCParseError, hand.New(runtime.ErrCodeInvalidBody))
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return jsonRPCErrorResponse(req.ID, JSONRPCInvalidRequest, hand.New(runtime.ErrCodeBadRequest))
+	}
+
+	isNotification := len(req.ID) == 0
+
+	var res *jsonRPCResponse
+
+	method, ok := s.GetMethod(req.Method)
+
+	var versionErr error
+	if ok {
+		method, _, versionErr = method.ResolveVersion(acceptVersionFromContext(ctx))
+	}
+
+	switch {
+	case !ok:
+		res = jsonRPCErrorResponse(req.ID, JSONRPCMethodNotFound, hand.New(runtime.ErrCodeMethodNotFound))
+
+	case versionErr != nil:
+		handErr := versionErr.(hand.E)
+		res = jsonRPCErrorResponse(req.ID, jsonRPCErrorCode(handErr.Code), handErr)
+
+	case s.RequiresAuthentication(method) && !authenticated:
+		res = jsonRPCErrorResponse(req.ID, JSONRPCServerError, hand.New(runtime.ErrCodeNoAuthentication))
+
+	default:
+		params := req.Params
+		if bytes.Equal(bytes.TrimSpace(params), []byte("null")) {
+			params = nil
+		}
+
+		result, err := s.Invoke(ctx, method, params)
+		if err != nil {
+			handErr, ok := err.(hand.E)
+			if !ok {
+				handErr = hand.New(runtime.ErrCodeUnknown)
+			}
+			res = jsonRPCErrorResponse(req.ID, jsonRPCErrorCode(handErr.Code), handErr)
+		} else {
+			res = &jsonRPCResponse{JSONRPC: "2.0", Result: result, ID: req.ID}
+		}
+	}
+
+	if isNotification {
+		return nil
+	}
+
+	return res
+}
+
+// JSONRPCErrorResponse encodes the error as the response to each request in a JSON-RPC 2.0 body, for failures such as
+// authentication which happen before the requests are dispatched. Requests which cannot be parsed are answered with a
+// null id. A nil response means the body contained only notifications.
+func (s *Service) JSONRPCErrorResponse(body []byte, err error) []byte {
+	handErr, ok := s.RedactError(err).(hand.E)
+	if !ok {
+		handErr = hand.New(runtime.ErrCodeUnknown)
+	}
+
+	trimmed := bytes.TrimSpace(body)
+
+	var batch []json.RawMessage
+	if len(trimmed) > 0 && trimmed[0] == '[' && json.Unmarshal(trimmed, &batch) == nil && len(batch) > 0 {
+		responses := []*jsonRPCResponse{}
+		for _, raw := range batch {
+			if res := jsonRPCRequestError(raw, handErr); res != nil {
+				responses = append(responses, res)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+
+		return encodeJSONRPC(responses)
+	}
+
+	res := jsonRPCRequestError(trimmed, handErr)
+	if res == nil {
+		return nil
+	}
+
+	return encodeJSONRPC(res)
+}
+
+// jsonRPCRequestError answers a single request with the error, or returns nil for a notification
+func jsonRPCRequestError(raw []byte, err hand.E) *jsonRPCResponse {
+	var req jsonRPCRequest
+	if json.Unmarshal(raw, &req) == nil && req.JSONRPC == "2.0" && req.Method != "" && len(req.ID) == 0 {
+		return nil
+	}
+
+	return jsonRPCErrorResponse(req.ID, jsonRPCErrorCode(err.Code), err)
+}
+
+func jsonRPCErrorResponse(id json.RawMessage, code int, err hand.E) *jsonRPCResponse {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+
+	message := err.Message
+	if message == "" {
+		message = err.Code
+	}
+
+	return &jsonRPCResponse{
+		JSONRPC: "2.0",
+		Error:   &jsonRPCError{Code: code, Message: message, Data: err},
+		ID:      id,
+	}
+}
+
+// jsonRPCErrorCode maps runtime error codes onto the JSON-RPC 2.0 reserved codes where one applies
+func jsonRPCErrorCode(code string) int {
+	switch code {
+	case runtime.ErrCodeMethodNotFound:
+		return JSONRPCMethodNotFound
+
+	case runtime.ErrCodeBadRequest:
+		fallthrough
+	case runtime.ErrCodeInvalidBody:
+		fallthrough
+	case runtime.ErrCodeSchemaFailure:
+		fallthrough
+	case runtime.ErrCodeMissingBody:
+		return JSONRPCInvalidParams
+
+	case runtime.ErrCodeUnknown:
+		return JSONRPCInternalError
+
+	default:
+		return JSONRPCServerError
+	}
+}
+
+func encodeJSONRPC(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(jsonRPCErrorResponse(nil, JSONRPCInternalError, hand.New(runtime.ErrCodeUnknown)))
+	}
+
+	return b
+}
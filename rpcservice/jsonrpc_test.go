@@ -0,0 +1,255 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type jsonRPCTestResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Data struct {
+			Code string `json:"code"`
+		} `json:"data"`
+	} `json:"error"`
+	ID json.RawMessage `json:"id"`
+}
+
+func decodeJSONRPC(t *testing.T, res []byte) jsonRPCTestResponse {
+	t.Helper()
+
+	var decoded jsonRPCTestResponse
+	if err := json.Unmarshal(res, &decoded); err != nil {
+		t.Fatalf("%v: %s", err, res)
+	}
+
+	return decoded
+}
+
+func TestJSONRPCResultAlwaysPresentOnSuccess(t *testing.T) {
+	svc := NewService(testLogger()).AddMethod("noop", noop, nil)
+
+	res := svc.HandleJSONRPC(testContext(), []byte(`{"jsonrpc":"2.0","method":"noop","id":1}`), false)
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(res, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if string(fields["result"]) != "null" {
+		t.Fatalf("expected null result, got %s", res)
+	}
+	if _, ok := fields["error"]; ok {
+		t.Fatalf("expected no error member, got %s", res)
+	}
+}
+
+func TestJSONRPCErrorOmitsResult(t *testing.T) {
+	svc := NewService(testLogger())
+
+	res := svc.HandleJSONRPC(testContext(), []byte(`{"jsonrpc":"2.0","method":"missing","id":1}`), false)
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(res, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["result"]; ok {
+		t.Fatalf("expected no result member, got %s", res)
+	}
+	if _, ok := fields["error"]; !ok {
+		t.Fatalf("expected error member, got %s", res)
+	}
+}
+
+func TestJSONRPCContextProviderErrorHasCode(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithFallibleContextProvider(func(ctx context.Context) (context.Context, error) {
+			return ctx, errors.New("database unavailable")
+		}).
+		AddMethod("noop", noop, nil)
+
+	res := svc.HandleJSONRPC(testContext(), []byte(`{"jsonrpc":"2.0","method":"noop","id":1}`), false)
+
+	var decoded struct {
+		Error struct {
+			Code int `json:"code"`
+			Data struct {
+				Code string `json:"code"`
+			} `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(res, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Error.Code != JSONRPCInternalError || decoded.Error.Data.Code != "unknown" {
+		t.Fatalf("expected unknown internal error, got %s", res)
+	}
+}
+
+func TestJSONRPCRequiresAuthentication(t *testing.T) {
+	svc := NewService(testLogger()).WithRequireAuthentication().AddMethod("noop", noop, nil)
+
+	res := svc.HandleJSONRPC(testContext(), []byte(`{"jsonrpc":"2.0","method":"noop","id":1}`), false)
+
+	var decoded struct {
+		Error *struct {
+			Data struct {
+				Code string `json:"code"`
+			} `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(res, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Error == nil || decoded.Error.Data.Code != "no_authentication" {
+		t.Fatalf("expected no_authentication error, got %s", res)
+	}
+}
+
+func TestJSONRPCResolvesVersion(t *testing.T) {
+	svc := NewService(testLogger()).
+		AddMethod("get", versionHandler("1"), nil).
+		AddMethodVersion("get", "2", versionHandler("2"), nil)
+
+	body := []byte(`{"jsonrpc":"2.0","method":"get","id":1}`)
+
+	tests := []struct {
+		requested string
+		result    string
+		code      string
+	}{
+		{"", `{"version":"2"}`, ""},
+		{"1", `{"version":"1"}`, ""},
+		{"3", "", "bad_request"},
+	}
+
+	for _, tt := range tests {
+		res := decodeJSONRPC(t, svc.HandleJSONRPC(WithAcceptVersion(testContext(), tt.requested), body, false))
+
+		if tt.code != "" {
+			if res.Error == nil || res.Error.Data.Code != tt.code {
+				t.Fatalf("version %q: expected %s error, got %+v", tt.requested, tt.code, res)
+			}
+			continue
+		}
+		if string(res.Result) != tt.result {
+			t.Fatalf("version %q: expected %s, got %s", tt.requested, tt.result, res.Result)
+		}
+	}
+}
+
+func TestJSONRPCErrorResponse(t *testing.T) {
+	svc := NewService(testLogger())
+	err := hand.New(runtime.ErrCodeInvalidToken)
+
+	single := decodeJSONRPC(t, svc.JSONRPCErrorResponse([]byte(`{"jsonrpc":"2.0","method":"noop","id":"a"}`), err))
+	if single.Error == nil || single.Error.Data.Code != "invalid_token" || string(single.ID) != `"a"` {
+		t.Fatalf("expected an invalid_token error for request a, got %+v", single)
+	}
+
+	unparsable := decodeJSONRPC(t, svc.JSONRPCErrorResponse([]byte(`not json`), err))
+	if unparsable.Error == nil || string(unparsable.ID) != "null" {
+		t.Fatalf("expected an error with a null id, got %+v", unparsable)
+	}
+
+	if res := svc.JSONRPCErrorResponse([]byte(`{"jsonrpc":"2.0","method":"noop"}`), err); res != nil {
+		t.Fatalf("expected no response to a notification, got %s", res)
+	}
+
+	batch := svc.JSONRPCErrorResponse([]byte(`[
+		{"jsonrpc":"2.0","method":"noop","id":1},
+		{"jsonrpc":"2.0","method":"noop"},
+		{"jsonrpc":"2.0","method":"noop","id":2}
+	]`), err)
+
+	var responses []jsonRPCTestResponse
+	if err := json.Unmarshal(batch, &responses); err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 2 || string(responses[0].ID) != "1" || string(responses[1].ID) != "2" {
+		t.Fatalf("expected errors for requests 1 and 2, got %s", batch)
+	}
+}
+
+func TestWrapAPIGatewayJSONRPC(t *testing.T) {
+	body := `{"jsonrpc":"2.0","method":"get","id":1}`
+
+	newService := func() *Service {
+		return NewService(testLogger()).
+			AddMethod("get", versionHandler("1"), nil).
+			AddMethodVersion("get", "2", versionHandler("2"), nil)
+	}
+
+	t.Run("base64 body and version header", func(t *testing.T) {
+		event := events.APIGatewayV2HTTPRequest{
+			Body:            base64.StdEncoding.EncodeToString([]byte(body)),
+			IsBase64Encoded: true,
+			Headers:         map[string]string{"accept-version": "1"},
+		}
+		event.RequestContext.RequestID = "req_1"
+
+		res, err := newService().WrapAPIGatewayJSONRPC()(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded := decodeJSONRPC(t, []byte(res.Body)); string(decoded.Result) != `{"version":"1"}` {
+			t.Fatalf("expected version 1, got %s", res.Body)
+		}
+	})
+
+	t.Run("oversized body", func(t *testing.T) {
+		event := events.APIGatewayV2HTTPRequest{Body: body + strings.Repeat(" ", 64)}
+		event.RequestContext.RequestID = "req_1"
+
+		res, err := newService().WithMaxRequestBytes(len(body)).WrapAPIGatewayJSONRPC()(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded := decodeJSONRPC(t, []byte(res.Body)); decoded.Error == nil || decoded.Error.Data.Code != "bad_request" {
+			t.Fatalf("expected a bad_request error, got %s", res.Body)
+		}
+	})
+
+	t.Run("near deadline", func(t *testing.T) {
+		event := events.APIGatewayV2HTTPRequest{Body: body}
+		event.RequestContext.RequestID = "req_1"
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		res, err := newService().WithMinTimeRemaining(time.Minute).WrapAPIGatewayJSONRPC()(ctx, event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := decodeJSONRPC(t, []byte(res.Body))
+		if decoded.Error == nil || decoded.Error.Data.Code != runtime.ErrCodeInsufficientTime || string(decoded.ID) != "1" {
+			t.Fatalf("expected an insufficient_time error for request 1, got %s", res.Body)
+		}
+	})
+
+	t.Run("warmup", func(t *testing.T) {
+		var warmed bool
+		svc := newService().WithWarmup(IsWarmerRequest, func(ctx context.Context) error {
+			warmed = true
+			return nil
+		})
+
+		res, err := svc.WrapAPIGatewayJSONRPC()(context.Background(), events.APIGatewayV2HTTPRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !warmed || res.StatusCode != http.StatusOK || res.Body != "" {
+			t.Fatalf("expected the warmer to run without dispatching, got %d %s", res.StatusCode, res.Body)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package rpcservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxRequestDepth is the default limit on JSON nesting depth of request bodies
+	DefaultMaxRequestDepth = 64
+	// DefaultMaxRequestTokens is the default limit on the number of JSON tokens in request bodies
+	DefaultMaxRequestTokens = 100000
+	// DefaultMaxRequestBytes is the default limit on decoded request bodies received from API Gateway
+	DefaultMaxRequestBytes = 6000000
+	// DefaultMaxResponseBytes is the default limit on encoded response bodies, just under the API Gateway payload limit of 6MB
+	DefaultMaxResponseBytes = 6000000
+)
+
+// checkRequestSize rejects request bodies larger than maxBytes
+func checkRequestSize(body []byte, maxBytes int) error {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxRequestBytes
+	}
+	if len(body) > maxBytes {
+		return hand.New(runtime.ErrCodeBadRequest).WithMessagef("request body exceeds %d bytes", maxBytes)
+	}
+
+	return nil
+}
+
+// checkRequestLimits scans a JSON body without decoding it fully, rejecting pathological nesting depth or size.
+// Malformed JSON is not reported here, it is left to schema validation and unmarshalling.
+func checkRequestLimits(body []byte, maxDepth, maxTokens int) error {
+	if len(body) == 0 {
+		return nil
+	}
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxRequestDepth
+	}
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxRequestTokens
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	depth, tokens := 0, 0
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		tokens++
+		if tokens > maxTokens {
+			return hand.New(runtime.ErrCodeBadRequest).WithMessagef("request body exceeds %d json tokens", maxTokens)
+		}
+
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+				if depth > maxDepth {
+					return hand.New(runtime.ErrCodeBadRequest).WithMessagef("request body exceeds json depth of %d", maxDepth)
+				}
+			case '}', ']':
+				depth--
+			}
+		}
+	}
+}
+
+// checkJSONSyntax returns an invalid_body error locating the syntax error in a malformed JSON body, so clients can
+// find the mistake rather than receiving a generic schema failure
+func checkJSONSyntax(body []byte) error {
+	if json.Valid(body) {
+		return nil
+	}
+
+	var doc interface{}
+	err := json.Unmarshal(body, &doc)
+
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return hand.New(runtime.ErrCodeInvalidBody).WithMessage("body is not valid json")
+	}
+
+	offset := int(syntaxErr.Offset)
+	return hand.New(runtime.ErrCodeInvalidBody).
+		WithMessagef("malformed json at offset %d: %s", offset, syntaxErr.Error()).
+		WithMeta(hand.M{
+			"offset":  offset,
+			"snippet": jsonSnippet(body, offset),
+		})
+}
+
+// jsonSnippet returns the body surrounding an offset, up to 20 bytes either side
+func jsonSnippet(body []byte, offset int) string {
+	start, end := offset-20, offset+20
+	if start < 0 {
+		start = 0
+	}
+	if end > len(body) {
+		end = len(body)
+	}
+	if start > end {
+		start = end
+	}
+
+	return string(body[start:end])
+}
+
+// CheckResponseSize returns a response_too_large error, and logs the size, when an encoded response body exceeds the
+// service's limit. Execution environments call it after encoding so oversized responses fail with a clear error.
+func (s *Service) CheckResponseSize(ctx context.Context, m *Method, size int) error {
+	maxBytes := s.MaxResponseBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxResponseBytes
+	}
+	if size <= maxBytes {
+		return nil
+	}
+
+	entry := s.Logger
+	if reqLogger := logger.FromContext(ctx); reqLogger != nil {
+		entry = reqLogger.Entry()
+	}
+
+	err := hand.New(runtime.ErrCodeResponseTooLarge)
+
+	entry.WithError(err).WithFields(logrus.Fields{
+		"method":         m.Name,
+		"response_bytes": size,
+		"max_bytes":      maxBytes,
+	}).Error("rpc response too large")
+
+	return err
+}
@@ -0,0 +1,207 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type listResponse struct {
+	Items []string `json:"items"`
+}
+
+func listItems(ctx context.Context) (*listResponse, error) {
+	return &listResponse{Items: []string{"first", "second", "third"}}, nil
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	tests := []struct {
+		maxBytes int
+		tooLarge bool
+	}{
+		{0, false},
+		{1000, false},
+		{10, true},
+	}
+
+	for _, tt := range tests {
+		log, hook := test.NewNullLogger()
+		svc := NewService(logrus.NewEntry(log)).WithMaxResponseBytes(tt.maxBytes).AddMethod("list", listItems, nil)
+
+		res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("list", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tooLarge := strings.Contains(res.Body, runtime.ErrCodeResponseTooLarge)
+		if tooLarge != tt.tooLarge {
+			t.Fatalf("max %d bytes: expected too large %t, got %d: %s", tt.maxBytes, tt.tooLarge, res.StatusCode, res.Body)
+		}
+		if !tt.tooLarge {
+			continue
+		}
+
+		var logged bool
+		for _, entry := range hook.AllEntries() {
+			if entry.Message == "rpc response too large" {
+				logged = entry.Data["method"] == "list" && entry.Data["response_bytes"].(int) > tt.maxBytes
+			}
+		}
+		if !logged {
+			t.Fatal("expected the oversized response to be logged with its method and size")
+		}
+	}
+}
+
+func TestAPIGatewayRequestBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		base64   bool
+		maxBytes int
+		code     string
+	}{
+		{"plain", string(echoJSON), false, 0, ""},
+		{"base64 encoded", base64.StdEncoding.EncodeToString(echoJSON), true, 0, ""},
+		{"invalid base64", "not base64!", true, 0, runtime.ErrCodeInvalidBody},
+		{"decoded body over the limit", base64.StdEncoding.EncodeToString(echoJSON), true, 20, runtime.ErrCodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(testLogger()).WithMaxRequestBytes(tt.maxBytes).AddMethod("echo", echo, echoSchema)
+
+			event := httpEvent("echo", nil)
+			event.Body = tt.body
+			event.IsBase64Encoded = tt.base64
+
+			res, err := svc.WrapAPIGatewayHTTP()(context.Background(), event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.code == "" {
+				if res.StatusCode != http.StatusOK || !strings.Contains(res.Body, `"name":"benchmark"`) {
+					t.Fatalf("expected the decoded body to be echoed, got %d: %s", res.StatusCode, res.Body)
+				}
+				return
+			}
+			if res.StatusCode != http.StatusBadRequest || !strings.Contains(res.Body, tt.code) {
+				t.Fatalf("expected 400 %s, got %d: %s", tt.code, res.StatusCode, res.Body)
+			}
+		})
+	}
+}
+
+func TestJSONSyntaxError(t *testing.T) {
+	svc, method := echoService()
+
+	_, err := svc.Invoke(testContext(), method, []byte(`{"name": "alice", "count": 3,, "tags": []}`))
+
+	herr, ok := err.(hand.E)
+	if !ok || herr.Code != runtime.ErrCodeInvalidBody {
+		t.Fatalf("expected invalid_body, got %v", err)
+	}
+	if herr.Meta["offset"] != 30 {
+		t.Errorf("expected the offset of the stray comma, got %v", herr.Meta["offset"])
+	}
+	if snippet, _ := herr.Meta["snippet"].(string); !strings.Contains(snippet, "3,,") {
+		t.Errorf("expected a snippet around the error, got %q", snippet)
+	}
+	if !strings.Contains(herr.Message, "offset 30") {
+		t.Errorf("expected the message to locate the error, got %q", herr.Message)
+	}
+}
+
+func TestJSONSnippetBounds(t *testing.T) {
+	body := []byte(`{"a":`)
+
+	if snippet := jsonSnippet(body, 0); snippet != `{"a":` {
+		t.Errorf("expected the start of the body, got %q", snippet)
+	}
+	if snippet := jsonSnippet(body, 100); snippet != "" {
+		t.Errorf("expected an empty snippet past the end, got %q", snippet)
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	tags := make([]string, 200)
+	for i := range tags {
+		tags[i] = `"t"`
+	}
+
+	tests := []struct {
+		name string
+		svc  *Service
+		body string
+		code string
+	}{
+		{
+			name: "deeply nested body",
+			svc:  NewService(testLogger()),
+			body: `{"name": "a", "tags": ` + strings.Repeat("[", DefaultMaxRequestDepth) + strings.Repeat("]", DefaultMaxRequestDepth) + `}`,
+			code: runtime.ErrCodeBadRequest,
+		},
+		{
+			name: "nesting within a lowered limit",
+			svc:  NewService(testLogger()).WithRequestLimits(2, 0),
+			body: `{"name": "a", "tags": ["t"]}`,
+		},
+		{
+			name: "nesting beyond a lowered limit",
+			svc:  NewService(testLogger()).WithRequestLimits(2, 0),
+			body: `{"name": "a", "tags": [["t"]]}`,
+			code: runtime.ErrCodeBadRequest,
+		},
+		{
+			name: "oversized array",
+			svc:  NewService(testLogger()).WithRequestLimits(0, 100),
+			body: `{"name": "a", "tags": [` + strings.Join(tags, ",") + `]}`,
+			code: runtime.ErrCodeBadRequest,
+		},
+		{
+			name: "array within the token limit",
+			svc:  NewService(testLogger()).WithRequestLimits(0, 100),
+			body: `{"name": "a", "tags": [` + strings.Join(tags[:50], ",") + `]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.svc.AddMethod("echo", echo, echoSchema)
+
+			res, err := tt.svc.WrapAPIGatewayHTTP()(context.Background(), events.APIGatewayV2HTTPRequest{
+				PathParameters: map[string]string{"method": "echo"},
+				Body:           tt.body,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.code == "" {
+				if res.StatusCode != http.StatusOK {
+					t.Fatalf("expected success, got %d: %s", res.StatusCode, res.Body)
+				}
+				return
+			}
+
+			var herr hand.E
+			if err := json.Unmarshal([]byte(res.Body), &herr); err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != http.StatusBadRequest || herr.Code != tt.code {
+				t.Fatalf("expected %s, got %d: %s", tt.code, res.StatusCode, res.Body)
+			}
+		})
+	}
+}
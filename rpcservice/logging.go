@@ -0,0 +1,31 @@
+package rpcservice
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fieldsPool reuses the field maps built for per-request log entries. logrus copies fields into the entry, so a map
+// can be returned to the pool as soon as the entry has been written.
+var fieldsPool = sync.Pool{
+	New: func() interface{} {
+		return logrus.Fields{}
+	},
+}
+
+func getFields() logrus.Fields {
+	return fieldsPool.Get().(logrus.Fields)
+}
+
+func putFields(fields logrus.Fields) {
+	for key := range fields {
+		delete(fields, key)
+	}
+	fieldsPool.Put(fields)
+}
+
+// logEnabled reports whether an entry logged at the level would be written, so callers can skip building its fields
+func logEnabled(entry *logrus.Entry, level logrus.Level) bool {
+	return entry != nil && entry.Logger.IsLevelEnabled(level)
+}
@@ -0,0 +1,88 @@
+package rpcservice
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/g-wilson/runtime/logger"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestCompletionLogLevels(t *testing.T) {
+	tests := []struct {
+		level       logrus.Level
+		completions int
+	}{
+		{logrus.DebugLevel, 1},
+		{logrus.InfoLevel, 1},
+		{logrus.WarnLevel, 0},
+	}
+
+	for _, tc := range tests {
+		log, hook := test.NewNullLogger()
+		log.Level = tc.level
+
+		svc := NewService(logrus.NewEntry(log)).AddMethod("echo", echo, echoSchema)
+		method, _ := svc.GetMethod("echo")
+
+		for i := 0; i < 2; i++ {
+			// the second request reuses pooled fields, which must not carry anything over
+			hook.Reset()
+			if _, err := svc.Invoke(logger.SetContext(testContext(), logrus.NewEntry(log)), method, echoJSON); err != nil {
+				t.Fatal(err)
+			}
+
+			var completions []logrus.Entry
+			for _, entry := range hook.AllEntries() {
+				if entry.Message == "rpc request completed" {
+					completions = append(completions, *entry)
+				}
+			}
+
+			if len(completions) != tc.completions {
+				t.Fatalf("%s: expected %d completion log entries, got %d", tc.level, tc.completions, len(completions))
+			}
+			if tc.completions == 0 {
+				continue
+			}
+
+			entry := completions[0]
+			if entry.Data["method"] != "echo" || entry.Data["code"] != "ok" ||
+				entry.Data["schema_valid"] != true || entry.Data["request_bytes"] != len(echoJSON) {
+				t.Fatalf("%s: unexpected completion log %v", tc.level, entry.Data)
+			}
+		}
+	}
+}
+
+func benchmarkInvokeLogLevel(b *testing.B, level logrus.Level) {
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	log.Level = level
+
+	svc := NewService(logrus.NewEntry(log)).AddMethod("echo", echo, echoSchema)
+	method, _ := svc.GetMethod("echo")
+	ctx := logger.SetContext(testContext(), logrus.NewEntry(log))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := svc.Invoke(ctx, method, echoJSON); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkInvokeInfoLevel measures the hot path of a production service, where debug entries are suppressed and
+// the completion log is written
+func BenchmarkInvokeInfoLevel(b *testing.B) {
+	benchmarkInvokeLogLevel(b, logrus.InfoLevel)
+}
+
+// BenchmarkInvokeWarnLevel measures requests whose completion log is suppressed, so no fields are built
+func BenchmarkInvokeWarnLevel(b *testing.B) {
+	benchmarkInvokeLogLevel(b, logrus.WarnLevel)
+}
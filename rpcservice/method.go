@@ -1,74 +1,205 @@
 package rpcservice
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
+	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/g-wilson/runtime"
 	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/internal/stacktrace"
 	"github.com/g-wilson/runtime/logger"
 
 	"github.com/sirupsen/logrus"
-	"github.com/xeipuuv/gojsonschema"
 )
 
 // Method holds properties about an RPC method as well as the handler function itself
 type Method struct {
 	Name                string
+	Version             string
+	Versions            []*Method
 	Handler             interface{}
-	CompiledSchema      *gojsonschema.Schema
+	CompiledSchema      CompiledSchema
+	Deprecation         *Deprecation
+	AllowAnonymous      bool
+	Idempotent          bool
+	HTTPVerb            string
+	SuccessStatus       int
+	WithoutValidation   bool
+	NDJSON              bool
+	Audited             bool
+	Examples            []Example
+	LogSampleRate       int
+	Limiter             Limiter
+	service             *Service
+	sampleCount         uint32
+	isFallback          bool
 	expectsRequestBody  bool
 	expectsResponseBody bool
 }
 
+// Deprecation holds lifecycle metadata for a method which is scheduled for removal
+type Deprecation struct {
+	Sunset      time.Time
+	Replacement string
+}
+
+// WithDeprecated marks the method as deprecated, with an optional sunset date and replacement method name
+func (m *Method) WithDeprecated(sunset time.Time, replacement string) *Method {
+	deprecation := &Deprecation{
+		Sunset:      sunset,
+		Replacement: replacement,
+	}
+	return m.apply(func(m *Method) { m.Deprecation = deprecation })
+}
+
+// Example is a sample request and response for a method, used to generate documentation and client mocks
+type Example struct {
+	Request  interface{}
+	Response interface{}
+}
+
+// WithExample adds an example request and response to the method.
+// The request is validated against the method's schema, panicking if it does not conform so stale examples fail fast.
+func (m *Method) WithExample(req, res interface{}) *Method {
+	if m.CompiledSchema != nil {
+		reqBytes, err := json.Marshal(req)
+		if err != nil {
+			panic(fmt.Errorf("runtime cannot encode example request for method %s: %w", m.Name, err))
+		}
+
+		if err := m.service.validator().Validate(m.CompiledSchema, reqBytes); err != nil {
+			panic(fmt.Errorf("runtime example request for method %s does not match schema: %w", m.Name, err))
+		}
+	}
+
+	if _, err := json.Marshal(res); err != nil {
+		panic(fmt.Errorf("runtime cannot encode example response for method %s: %w", m.Name, err))
+	}
+
+	m.Examples = append(m.Examples, Example{Request: req, Response: res})
+	return m
+}
+
+// WithIdempotent marks the method as safe to retry: invoking it more than once with the same request has the same
+// effect as invoking it once. It is advertised by the schema endpoint and the X-Idempotent response header so that
+// clients and gateways may retry it automatically. Methods are assumed to be unsafe to retry unless marked.
+func (m *Method) WithIdempotent() *Method {
+	return m.apply(func(m *Method) { m.Idempotent = true })
+}
+
+// WithHTTPVerb declares the HTTP method which best describes the RPC method, such as GET or DELETE. The dev server
+// serves the method under the verb as well as POST, reading the request from the query string for GET.
+func (m *Method) WithHTTPVerb(verb string) *Method {
+	return m.apply(func(m *Method) { m.HTTPVerb = strings.ToUpper(verb) })
+}
+
+// WithSuccessStatus sets the HTTP status of responses with a result, such as 201 Created. Methods without a result
+// still respond with 204 No Content.
+func (m *Method) WithSuccessStatus(status int) *Method {
+	return m.apply(func(m *Method) { m.SuccessStatus = status })
+}
+
+// SuccessStatusCode returns the HTTP status of responses with a result, defaulting to 200 OK
+func (m *Method) SuccessStatusCode() int {
+	if m.SuccessStatus == 0 {
+		return http.StatusOK
+	}
+	return m.SuccessStatus
+}
+
+// WithAllowAnonymous permits unauthenticated requests to the method when the service requires authentication
+func (m *Method) WithAllowAnonymous() *Method {
+	return m.apply(func(m *Method) { m.AllowAnonymous = true })
+}
+
 // Invoke executes a handler method within a context
 func (m *Method) Invoke(ctx context.Context, body []byte) (interface{}, error) {
 	startedAt := time.Now()
 	reqLogger := logger.FromContext(ctx)
 
-	reqLogger.Update(reqLogger.Entry().WithFields(logrus.Fields{
-		"rpc_method": m.Name,
-	}))
+	reqLogger.Update(reqLogger.Entry().WithContext(ctx).WithField("rpc_method", m.Name))
+
+	if m.Deprecation != nil && logEnabled(reqLogger.Entry(), logrus.WarnLevel) {
+		depLogger := reqLogger.Entry()
+		if !m.Deprecation.Sunset.IsZero() {
+			depLogger = depLogger.WithField("sunset", m.Deprecation.Sunset.Format(time.RFC3339))
+		}
+		if m.Deprecation.Replacement != "" {
+			depLogger = depLogger.WithField("replacement", m.Deprecation.Replacement)
+		}
+
+		depLogger.Warn("rpc deprecated method invoked")
+	}
 
 	handlerValue := reflect.ValueOf(m.Handler)
 	handlerType := handlerValue.Type()
 
-	if m.CompiledSchema != nil {
-		schemaResult, err := m.CompiledSchema.Validate(gojsonschema.NewBytesLoader(body))
-		if err != nil {
-			return nil, hand.New("invalid_body")
+	if len(body) > 0 && !m.NDJSON && !m.isFallback {
+		if err := checkJSONSyntax(body); err != nil {
+			reqLogger.Entry().
+				WithError(err).
+				WithField("handler_duration", getDuration(startedAt)).
+				Warn("rpc request handled error")
+
+			return nil, err
 		}
-		if !schemaResult.Valid() {
-			errs := schemaResult.Errors()
+	}
+
+	if m.CompiledSchema != nil && !m.NDJSON {
+		err := m.service.validator().Validate(m.CompiledSchema, body)
 
-			var reasons []map[string]string
+		if schemaErr, ok := err.(*SchemaError); ok {
+			var reasons []map[string]interface{}
 
-			for _, err := range errs {
-				reasons = append(reasons, map[string]string{
-					"field":   err.Field(),
-					"type":    err.Type(),
-					"message": err.Description(),
+			for _, reason := range schemaErr.Reasons {
+				reasons = append(reasons, map[string]interface{}{
+					"field":   reason.Field,
+					"type":    reason.Type,
+					"message": reason.Message,
+					"keyword": reason.Keyword,
+					"params":  reason.Params,
 				})
 			}
 
-			err := hand.New("schema_fail").WithMeta(hand.M{"reasons": reasons})
+			err := hand.New(runtime.ErrCodeSchemaFailure).WithMeta(hand.M{"reasons": reasons})
 
-			reqLogger.Entry().
-				WithError(err).
-				WithField("handler_duration", getDuration(startedAt)).
-				Warn("rpc request handled error")
+			if logEnabled(reqLogger.Entry(), logrus.WarnLevel) {
+				reqLogger.Entry().
+					WithError(err).
+					WithField("handler_duration", getDuration(startedAt)).
+					Warn("rpc request handled error")
+			}
 
 			return nil, err
 		}
+		if err != nil {
+			return nil, hand.New(runtime.ErrCodeInvalidBody)
+		}
 	}
 
 	var result []reflect.Value
-
-	if len(body) > 0 {
+	var err error
+
+	if m.isFallback {
+		result, err = m.call(ctx, handlerValue, []reflect.Value{
+			reflect.ValueOf(ctx),
+			reflect.ValueOf(m.Name),
+			reflect.ValueOf(body),
+		})
+	} else if m.NDJSON {
+		result, err = m.call(ctx, handlerValue, []reflect.Value{
+			reflect.ValueOf(ctx),
+			reflect.ValueOf(newNDJSONReader(ctx, m, body)),
+		})
+	} else if len(body) > 0 {
 		if !m.expectsRequestBody {
 			reqLogger.Entry().
 				WithError(hand.New("request_body_not_expected")).
@@ -79,7 +210,16 @@ func (m *Method) Invoke(ctx context.Context, body []byte) (interface{}, error) {
 		}
 
 		req := reflect.New(handlerType.In(1).Elem())
-		err := json.Unmarshal(body, req.Interface())
+		err = m.decodeBody(body, req.Interface())
+		if handErr, ok := err.(hand.E); ok {
+			reqLogger.Entry().
+				WithError(handErr).
+				WithField("err_message", handErr.Message).
+				WithField("handler_duration", getDuration(startedAt)).
+				Warn("rpc request handled error")
+
+			return nil, handErr
+		}
 		if err != nil {
 			reqLogger.Entry().
 				WithError(fmt.Errorf("error parsing request body: %w", err)).
@@ -89,7 +229,7 @@ func (m *Method) Invoke(ctx context.Context, body []byte) (interface{}, error) {
 			return nil, hand.New("invalid_body").WithMessage("body parsing error")
 		}
 
-		result = handlerValue.Call([]reflect.Value{reflect.ValueOf(ctx), req})
+		result, err = m.call(ctx, handlerValue, []reflect.Value{reflect.ValueOf(ctx), req})
 	} else {
 		if m.expectsRequestBody {
 			reqLogger.Entry().
@@ -100,7 +240,11 @@ func (m *Method) Invoke(ctx context.Context, body []byte) (interface{}, error) {
 			return nil, hand.New("invalid_body").WithMessage("rpc method expects a request body")
 		}
 
-		result = handlerValue.Call([]reflect.Value{reflect.ValueOf(ctx)})
+		result, err = m.call(ctx, handlerValue, []reflect.Value{reflect.ValueOf(ctx)})
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
 	reqLogger.Update(reqLogger.Entry().WithField("handler_duration", getDuration(startedAt)))
@@ -108,29 +252,35 @@ func (m *Method) Invoke(ctx context.Context, body []byte) (interface{}, error) {
 	resultErr := result[len(result)-1]
 
 	if resultErr.IsNil() {
-		reqLogger.Entry().Info("rpc request handled")
+		if !isSampledOut(ctx) {
+			reqLogger.Entry().Info("rpc request handled")
+		}
+
+		// a nil pointer result is no result, so it is served as no content rather than null
+		if len(result) == 1 || result[0].IsNil() {
+			return nil, nil
+		}
 
 		return result[0].Interface(), nil
 	}
 
-	err, _ := resultErr.Interface().(error)
+	err, _ = resultErr.Interface().(error)
 
 	reqLogger.Update(reqLogger.Entry().WithError(err))
 
 	if handErr, ok := err.(hand.E); ok {
 		if handErr.Err != nil {
-			reqLogger.Update(reqLogger.Entry().WithField("err_cause", handErr.Err))
+			reqLogger.Update(reqLogger.Entry().WithField("err_cause", handErr.CauseDetail()))
 		}
 		if handErr.Message != "" {
 			reqLogger.Update(reqLogger.Entry().WithField("err_message", handErr.Message))
 		}
-
-		if handErr.Code == runtime.ErrCodeUnknown {
-			reqLogger.Entry().Error("rpc request handled error")
-		} else {
-			reqLogger.Entry().Warn("rpc request handled error")
+		if len(handErr.LogMeta) > 0 {
+			reqLogger.Update(reqLogger.Entry().WithFields(logrus.Fields(handErr.LogMeta)))
 		}
 
+		reqLogger.Entry().Log(m.service.ErrorLogLevel(handErr.Code), "rpc request handled error")
+
 		return nil, handErr
 	}
 
@@ -139,6 +289,55 @@ func (m *Method) Invoke(ctx context.Context, body []byte) (interface{}, error) {
 	return nil, hand.New(runtime.ErrCodeUnknown)
 }
 
+// decodeBody unmarshals the request body, rejecting unknown fields if the service disallows them and decoding
+// numbers in untyped fields as json.Number if the service asks for it
+func (m *Method) decodeBody(body []byte, dest interface{}) error {
+	if m.service == nil || (!m.service.DisallowUnknownFields && !m.service.UseNumber) {
+		return json.Unmarshal(body, dest)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if m.service.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if m.service.UseNumber {
+		dec.UseNumber()
+	}
+
+	err := dec.Decode(dest)
+	if err != nil && strings.HasPrefix(err.Error(), "json: unknown field ") {
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return hand.New(runtime.ErrCodeBadRequest).
+			WithMessagef("unknown field %s", field).
+			WithMeta(hand.M{"field": field})
+	}
+
+	return err
+}
+
+// call executes the handler, recovering from panics so they are logged with structured fields and reported as unknown errors
+func (m *Method) call(ctx context.Context, fn reflect.Value, args []reflect.Value) (result []reflect.Value, err error) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+
+		requestID, _ := runtime.RequestIDFromContext(ctx)
+
+		logger.FromContext(ctx).Entry().WithFields(logrus.Fields{
+			"panic":      fmt.Sprint(p),
+			"stack":      stacktrace.Format(debug.Stack(), m.service.panicStackDepth()),
+			"method":     m.Name,
+			"request_id": requestID,
+		}).Error("rpc request panic recovered")
+
+		err = hand.New(runtime.ErrCodeUnknown)
+	}()
+
+	return fn.Call(args), nil
+}
+
 // validateMethod analyses a Method for requirements before it can be served
 // valid handler functions:
 // func(ctx context.Context, request *T) (response *T, err error)
@@ -166,6 +365,11 @@ func validateMethod(method *Method) (hasReqBody, hasResBody bool, err error) {
 		return
 	}
 
+	if method.NDJSON && numArgs != 2 {
+		err = errors.New("ndjson methods must take *rpcservice.NDJSONReader as their second argument")
+		return
+	}
+
 	firstArg := handlerType.In(0)
 	if !firstArg.Implements(contextType) {
 		err = fmt.Errorf("handler first argument must implement context, %s provided", firstArg.Kind())
@@ -186,15 +390,21 @@ func validateMethod(method *Method) (hasReqBody, hasResBody bool, err error) {
 			return
 		}
 
+		if (secondArg == ndjsonReaderType) != method.NDJSON {
+			err = errors.New("ndjson methods must take *rpcservice.NDJSONReader as their second argument")
+			return
+		}
+
 		secondArgPtrType := secondArg.Elem()
-		if secondArgPtrType.Kind() != reflect.Struct {
+		isRawBody := method.WithoutValidation && secondArgPtrType == rawMessageType
+		if secondArgPtrType.Kind() != reflect.Struct && !isRawBody {
 			err = fmt.Errorf("handler second argument must be struct, %s provided", secondArgPtrType.Kind())
 			return
 		}
 
 		hasReqBody = true
 
-		if method.CompiledSchema == nil {
+		if method.CompiledSchema == nil && !method.WithoutValidation {
 			err = errors.New("methods with a request type must provide a schema")
 			return
 		}
@@ -0,0 +1,71 @@
+package rpcservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"strings"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ContentTypeMsgpack is the media type for MessagePack request and response bodies
+const ContentTypeMsgpack = "application/msgpack"
+
+// DecodeRequestBody returns a JSON request body for Invoke. When the service accepts msgpack and the content type
+// is msgpack, the body is decoded into its generic form and re-encoded as JSON so that schema validation still applies.
+func (s *Service) DecodeRequestBody(contentType string, body []byte) ([]byte, error) {
+	if !s.EnableMsgpack || len(body) == 0 || !isMediaType(contentType, ContentTypeMsgpack) {
+		return body, nil
+	}
+
+	var doc interface{}
+	if err := msgpack.Unmarshal(body, &doc); err != nil {
+		return nil, hand.New(runtime.ErrCodeInvalidBody).WithMessage("msgpack parsing error")
+	}
+
+	jsonBody, err := json.Marshal(doc)
+	if err != nil {
+		return nil, hand.New(runtime.ErrCodeInvalidBody).WithMessage("msgpack body cannot be represented as json")
+	}
+
+	return jsonBody, nil
+}
+
+// EncodeResponseBody encodes a handler result as msgpack if the service allows it and the Accept header asks for it,
+// otherwise as JSON. Struct fields are named by their json tags in both formats.
+func (s *Service) EncodeResponseBody(accept string, result interface{}) (body []byte, contentType string, err error) {
+	if s.EnableMsgpack && acceptsMediaType(accept, ContentTypeMsgpack) {
+		var buf bytes.Buffer
+
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetCustomStructTag("json")
+
+		if err := enc.Encode(result); err != nil {
+			return nil, "", err
+		}
+
+		return buf.Bytes(), ContentTypeMsgpack, nil
+	}
+
+	body, err = json.Marshal(result)
+	return body, "application/json; charset=utf-8", err
+}
+
+func isMediaType(header, mediaType string) bool {
+	parsed, _, err := mime.ParseMediaType(header)
+	return err == nil && strings.EqualFold(parsed, mediaType)
+}
+
+func acceptsMediaType(accept, mediaType string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		if isMediaType(strings.TrimSpace(part), mediaType) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,118 @@
+package rpcservice
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+type echoRequest struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+}
+
+type echoResponse struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+}
+
+func echo(ctx context.Context, req *echoRequest) (*echoResponse, error) {
+	return &echoResponse{Name: req.Name, Count: req.Count, Tags: req.Tags}, nil
+}
+
+var echoSchema = gojsonschema.NewStringLoader(`{
+	"type": "object",
+	"required": ["name"],
+	"properties": {
+		"name": {"type": "string"},
+		"count": {"type": "integer"},
+		"tags": {"type": "array", "items": {"type": "string"}}
+	}
+}`)
+
+var echoJSON = []byte(`{"name": "benchmark", "count": 3, "tags": ["a", "b", "c"]}`)
+
+func echoService() (*Service, *Method) {
+	svc := NewService(testLogger()).WithMsgpack().AddMethod("echo", echo, echoSchema)
+	method, _ := svc.GetMethod("echo")
+
+	return svc, method
+}
+
+func echoMsgpack(tb testing.TB) []byte {
+	body, err := msgpack.Marshal(map[string]interface{}{"name": "benchmark", "count": 3, "tags": []string{"a", "b", "c"}})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return body
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	svc, method := echoService()
+
+	body, err := svc.DecodeRequestBody(ContentTypeMsgpack, echoMsgpack(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := svc.Invoke(testContext(), method, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resBytes, contentType, err := svc.EncodeResponseBody(ContentTypeMsgpack, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != ContentTypeMsgpack {
+		t.Fatalf("expected a msgpack response, got %s", contentType)
+	}
+
+	var res echoResponse
+	dec := msgpack.NewDecoder(bytes.NewReader(resBytes))
+	dec.SetCustomStructTag("json")
+	if err := dec.Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "benchmark" || res.Count != 3 || len(res.Tags) != 3 {
+		t.Fatalf("expected the request to be echoed, got %+v", res)
+	}
+}
+
+func benchmarkInvoke(b *testing.B, contentType string, body []byte) {
+	svc, method := echoService()
+	ctx := testContext()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		reqBody, err := svc.DecodeRequestBody(contentType, body)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		result, err := svc.Invoke(ctx, method, reqBody)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, _, err := svc.EncodeResponseBody(contentType, result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInvokeJSON(b *testing.B) {
+	benchmarkInvoke(b, "application/json", echoJSON)
+}
+
+func BenchmarkInvokeMsgpack(b *testing.B) {
+	benchmarkInvoke(b, ContentTypeMsgpack, echoMsgpack(b))
+}
@@ -0,0 +1,90 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
+)
+
+// Mux serves several Services from a single Lambda function, routing /{service}/{method} requests by service prefix.
+// Each service keeps its own logger, identity provider and settings.
+type Mux struct {
+	Logger   *logrus.Entry
+	Services map[string]*Service
+	handlers map[string]LambdaAPIGatewayHandler
+}
+
+// NewMux creates a Mux
+func NewMux(l *logrus.Entry) *Mux {
+	return &Mux{
+		Logger:   l,
+		Services: map[string]*Service{},
+		handlers: map[string]LambdaAPIGatewayHandler{},
+	}
+}
+
+// Handle serves a service under a path prefix
+func (m *Mux) Handle(prefix string, svc *Service) *Mux {
+	prefix = strings.Trim(prefix, "/")
+
+	m.Services[prefix] = svc
+	m.handlers[prefix] = svc.WrapAPIGatewayHTTP()
+	return m
+}
+
+// WrapAPIGatewayHTTP returns a Lambda compatible handler function which dispatches HTTP API Gateway requests to the
+// service matching the "service" path parameter, or the first segment of the path when there is no such parameter
+func (m *Mux) WrapAPIGatewayHTTP() LambdaAPIGatewayHandler {
+	return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayProxyResponse, error) {
+		prefix, methodName := splitServicePath(event)
+
+		handler, ok := m.handlers[prefix]
+		if !ok {
+			m.Logger.WithField("apig_request_id", event.RequestContext.RequestID).
+				WithError(fmt.Errorf("wrap mux: service with prefix %s not found", prefix)).
+				Warn("request failed")
+
+			handErr := hand.New(runtime.ErrCodeMethodNotFound)
+			body, _ := json.Marshal(handErr)
+
+			return events.APIGatewayProxyResponse{
+				StatusCode: runtime.HTTPStatus(handErr.Code),
+				Body:       string(body),
+				Headers: map[string]string{
+					"Content-Type": "application/json; charset=utf-8",
+				},
+			}, nil
+		}
+
+		if _, ok := event.PathParameters["method"]; !ok && methodName != "" {
+			params := map[string]string{"method": methodName}
+			for k, v := range event.PathParameters {
+				params[k] = v
+			}
+			event.PathParameters = params
+		}
+
+		return handler(ctx, event)
+	}
+}
+
+// splitServicePath finds the service prefix and method name of a request from its path parameters or raw path
+func splitServicePath(event events.APIGatewayV2HTTPRequest) (prefix, methodName string) {
+	if prefix, ok := event.PathParameters["service"]; ok {
+		return prefix, event.PathParameters["method"]
+	}
+
+	parts := strings.SplitN(strings.Trim(event.RawPath, "/"), "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+
+	return parts[0], ""
+}
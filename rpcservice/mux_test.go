@@ -0,0 +1,73 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type serviceNameResponse struct {
+	Service string `json:"service"`
+}
+
+func namedService(name string) *Service {
+	return NewService(testLogger()).AddMethod("whoami", func(ctx context.Context) (*serviceNameResponse, error) {
+		return &serviceNameResponse{Service: name}, nil
+	}, nil)
+}
+
+func TestMux(t *testing.T) {
+	handler := NewMux(testLogger()).
+		Handle("/users/", namedService("users")).
+		Handle("orders", namedService("orders")).
+		WrapAPIGatewayHTTP()
+
+	tests := []struct {
+		name   string
+		event  events.APIGatewayV2HTTPRequest
+		status int
+		body   string
+	}{
+		{
+			name:   "raw path",
+			event:  events.APIGatewayV2HTTPRequest{RawPath: "/users/whoami"},
+			status: http.StatusOK,
+			body:   `{"service":"users"}`,
+		},
+		{
+			name:   "path parameters",
+			event:  events.APIGatewayV2HTTPRequest{PathParameters: map[string]string{"service": "orders", "method": "whoami"}},
+			status: http.StatusOK,
+			body:   `{"service":"orders"}`,
+		},
+		{
+			name:   "unknown service",
+			event:  events.APIGatewayV2HTTPRequest{RawPath: "/billing/whoami"},
+			status: http.StatusNotFound,
+			body:   runtime.ErrCodeMethodNotFound,
+		},
+		{
+			name:   "unknown method",
+			event:  events.APIGatewayV2HTTPRequest{RawPath: "/users/missing"},
+			status: http.StatusNotFound,
+			body:   runtime.ErrCodeMethodNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := handler(context.Background(), tt.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tt.status || !strings.Contains(res.Body, tt.body) {
+				t.Fatalf("expected %d %s, got %d: %s", tt.status, tt.body, res.StatusCode, res.Body)
+			}
+		})
+	}
+}
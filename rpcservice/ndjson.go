@@ -0,0 +1,169 @@
+package rpcservice
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/internal/ctxkey"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ContentTypeNDJSON is the media type for newline-delimited JSON request bodies
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// maxNDJSONLineBytes limits the size of a single record, the body as a whole is limited by the execution environment
+const maxNDJSONLineBytes = 1024 * 1024
+
+var ndjsonBodyKey = ctxkey.New("ndjsonbody")
+
+var ndjsonReaderType = reflect.TypeOf(&NDJSONReader{})
+
+// NDJSONReader reads the records of a newline-delimited JSON request body one at a time, validating each against the
+// method's record schema. Handlers of NDJSON methods receive it in place of a request struct:
+// func(ctx context.Context, records *rpcservice.NDJSONReader) (response *Response, err error)
+type NDJSONReader struct {
+	scanner    *bufio.Scanner
+	method     *Method
+	line       int
+	record     []byte
+	lineErrors []NDJSONLineError
+	err        error
+}
+
+// NDJSONLineError describes a record which could not be decoded or did not conform to the record schema
+type NDJSONLineError struct {
+	Line    int                 `json:"line"`
+	Message string              `json:"message"`
+	Reasons []SchemaErrorReason `json:"reasons,omitempty"`
+}
+
+func (e NDJSONLineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// AddNDJSONMethod creates a Method which receives a newline-delimited JSON body as an *NDJSONReader and adds it to the
+// service. Each record is validated against recordSchema as it is read, rather than validating the body as a whole.
+func (s *Service) AddNDJSONMethod(methodName string, handler interface{}, recordSchema gojsonschema.JSONLoader) *Service {
+	sc, ok := s.registerSchema(methodName, recordSchema)
+	if !ok {
+		return s
+	}
+
+	return s.addMethod(&Method{
+		Name:           methodName,
+		Handler:        handler,
+		CompiledSchema: sc,
+		NDJSON:         true,
+		service:        s,
+	})
+}
+
+// WithNDJSONBody attaches a request body reader for an NDJSON method to the context, so the records are streamed to
+// the handler instead of being buffered. Invoke it with a nil body.
+func WithNDJSONBody(ctx context.Context, body io.Reader) context.Context {
+	return context.WithValue(ctx, ndjsonBodyKey, body)
+}
+
+func newNDJSONReader(ctx context.Context, m *Method, body []byte) *NDJSONReader {
+	var r io.Reader = bytes.NewReader(body)
+	if streamed, ok := ctx.Value(ndjsonBodyKey).(io.Reader); ok && len(body) == 0 {
+		r = streamed
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineBytes)
+
+	return &NDJSONReader{scanner: scanner, method: m}
+}
+
+// Next advances to the next record, skipping blank lines. It returns false when the body is exhausted or cannot be read.
+func (r *NDJSONReader) Next() bool {
+	for r.scanner.Scan() {
+		r.line++
+
+		record := bytes.TrimSpace(r.scanner.Bytes())
+		if len(record) == 0 {
+			continue
+		}
+
+		r.record = record
+		return true
+	}
+
+	r.record = nil
+	if err := r.scanner.Err(); err != nil {
+		r.err = hand.New(runtime.ErrCodeInvalidBody).WithMessagef("ndjson read failed after line %d", r.line).WithCause(err)
+	}
+
+	return false
+}
+
+// Line returns the line number of the current record, starting at 1
+func (r *NDJSONReader) Line() int {
+	return r.line
+}
+
+// Decode validates the current record and unmarshals it into dest. Invalid records, including records over the
+// service's depth and token limits, return an NDJSONLineError, which is also kept for LineErrors, so the handler can
+// skip the record and continue with the next.
+func (r *NDJSONReader) Decode(dest interface{}) error {
+	if r.record == nil {
+		return r.lineError(NDJSONLineError{Message: "no current record"})
+	}
+
+	svc := r.method.service
+	if err := checkRequestLimits(r.record, svc.MaxRequestDepth, svc.MaxRequestTokens); err != nil {
+		return r.lineError(NDJSONLineError{Message: err.(hand.E).Message})
+	}
+
+	if r.method.CompiledSchema != nil {
+		err := svc.validator().Validate(r.method.CompiledSchema, r.record)
+		if schemaErr, ok := err.(*SchemaError); ok {
+			return r.lineError(NDJSONLineError{Message: "record does not match schema", Reasons: schemaErr.Reasons})
+		}
+		if err != nil {
+			return r.lineError(NDJSONLineError{Message: "record is not valid json"})
+		}
+	}
+
+	if err := r.method.decodeBody(r.record, dest); err != nil {
+		return r.lineError(NDJSONLineError{Message: "record parsing error"})
+	}
+
+	return nil
+}
+
+func (r *NDJSONReader) lineError(lineErr NDJSONLineError) error {
+	lineErr.Line = r.line
+	r.lineErrors = append(r.lineErrors, lineErr)
+	return lineErr
+}
+
+// LineErrors returns the errors of every invalid record decoded so far
+func (r *NDJSONReader) LineErrors() []NDJSONLineError {
+	return r.lineErrors
+}
+
+// Err returns the error which stopped Next, if the body could not be read
+func (r *NDJSONReader) Err() error {
+	return r.err
+}
+
+// ValidationError returns a schema_failure error listing the invalid lines, or nil if every record was valid.
+// Handlers which reject a batch containing any invalid record can return it directly.
+func (r *NDJSONReader) ValidationError() error {
+	if len(r.lineErrors) == 0 {
+		return nil
+	}
+
+	return hand.New(runtime.ErrCodeSchemaFailure).
+		WithMessagef("%d invalid records", len(r.lineErrors)).
+		WithMeta(hand.M{"lines": r.lineErrors})
+}
@@ -0,0 +1,87 @@
+package rpcservice
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+type importRecord struct {
+	Name string      `json:"name"`
+	Tags interface{} `json:"tags"`
+}
+
+type importResponse struct {
+	Imported   []string
+	LineErrors []NDJSONLineError
+}
+
+func importRecords(ctx context.Context, records *NDJSONReader) (*importResponse, error) {
+	res := &importResponse{}
+	for records.Next() {
+		var record importRecord
+		if err := records.Decode(&record); err != nil {
+			continue
+		}
+		res.Imported = append(res.Imported, record.Name)
+	}
+	res.LineErrors = records.LineErrors()
+
+	return res, records.Err()
+}
+
+var importRecordSchema = gojsonschema.NewStringLoader(`{
+	"type": "object",
+	"required": ["name"],
+	"properties": {"name": {"type": "string"}}
+}`)
+
+func invokeImport(t *testing.T, svc *Service, body string) *importResponse {
+	svc.AddNDJSONMethod("import", importRecords, importRecordSchema)
+	method, _ := svc.GetMethod("import")
+
+	result, err := svc.Invoke(testContext(), method, []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result.(*importResponse)
+}
+
+func TestNDJSONInvalidLine(t *testing.T) {
+	body := strings.Join([]string{
+		`{"name": "alice"}`,
+		`{"name": 42}`,
+		``,
+		`{"name": "bob"}`,
+	}, "\n")
+
+	res := invokeImport(t, NewService(testLogger()), body)
+
+	if strings.Join(res.Imported, ",") != "alice,bob" {
+		t.Fatalf("expected the valid records to be imported, got %v", res.Imported)
+	}
+	if len(res.LineErrors) != 1 || res.LineErrors[0].Line != 2 || len(res.LineErrors[0].Reasons) == 0 {
+		t.Fatalf("expected a schema error for line 2, got %+v", res.LineErrors)
+	}
+}
+
+func TestNDJSONLimitsApplyPerRecord(t *testing.T) {
+	// every record is within the limits, but the body as a whole has more tokens than one record may
+	var lines []string
+	for i := 0; i < 5; i++ {
+		lines = append(lines, `{"name": "record", "tags": ["a", "b"]}`)
+	}
+	lines = append(lines, `{"name": "deep", "tags": [[[["x"]]]]}`)
+
+	res := invokeImport(t, NewService(testLogger()).WithRequestLimits(3, 10), strings.Join(lines, "\n"))
+
+	if len(res.Imported) != 5 {
+		t.Fatalf("expected the records within the limits to be imported, got %v", res.Imported)
+	}
+	if len(res.LineErrors) != 1 || res.LineErrors[0].Line != 6 {
+		t.Fatalf("expected the record over the depth limit to be rejected, got %+v", res.LineErrors)
+	}
+}
@@ -0,0 +1,95 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/logger"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func explode(ctx context.Context) error {
+	panic("boom")
+}
+
+func TestPanicStackLoggedNotReturned(t *testing.T) {
+	log, hook := test.NewNullLogger()
+
+	svc := NewService(logrus.NewEntry(log)).
+		WithEnvironment(EnvironmentDevelopment).
+		WithPanicStackDepth(2).
+		AddMethod("explode", explode, nil)
+
+	res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("explode", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != http.StatusInternalServerError || !strings.Contains(res.Body, runtime.ErrCodeUnknown) {
+		t.Fatalf("expected an unknown error, got %d: %s", res.StatusCode, res.Body)
+	}
+	for _, leak := range []string{"boom", "goroutine", ".go:", "panic"} {
+		if strings.Contains(res.Body, leak) {
+			t.Fatalf("expected no panic details in the response even in development, got %s", res.Body)
+		}
+	}
+
+	var stack string
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "rpc request panic recovered" {
+			stack, _ = entry.Data["stack"].(string)
+		}
+	}
+	if stack == "" {
+		t.Fatal("expected the panic to be logged with its stack")
+	}
+
+	// a frame is a function line and a file line, and the handler frames belong to this module so are filtered
+	frames := 0
+	for _, line := range strings.Split(stack, "\n") {
+		if !strings.HasPrefix(line, "\t") && !strings.HasSuffix(line, "more frames") {
+			frames++
+		}
+	}
+	if frames == 0 || frames > 2 {
+		t.Fatalf("expected the logged stack to be trimmed to 2 frames, got:\n%s", stack)
+	}
+	if strings.Contains(stack, "github.com/g-wilson/runtime") || strings.Contains(stack, "reflect.") {
+		t.Fatalf("expected runtime frames to be filtered from the logged stack, got:\n%s", stack)
+	}
+}
+
+func TestPanicLogFields(t *testing.T) {
+	log, hook := test.NewNullLogger()
+
+	svc := NewService(logrus.NewEntry(log)).AddMethod("explode", explode, nil)
+	method, _ := svc.GetMethod("explode")
+
+	ctx := runtime.WithRequestID(logger.SetContext(context.Background(), logrus.NewEntry(log)), "req_1")
+	if _, err := svc.Invoke(ctx, method, nil); err == nil {
+		t.Fatal("expected the panic to be returned as an error")
+	}
+
+	var entry *logrus.Entry
+	for _, e := range hook.AllEntries() {
+		if e.Message == "rpc request panic recovered" {
+			entry = e
+		}
+	}
+	if entry == nil {
+		t.Fatal("expected the panic to be logged")
+	}
+
+	if entry.Level != logrus.ErrorLevel || entry.Data["panic"] != "boom" || entry.Data["method"] != "explode" ||
+		entry.Data["request_id"] != "req_1" {
+		t.Fatalf("unexpected panic log fields %v", entry.Data)
+	}
+	if stack, _ := entry.Data["stack"].(string); stack == "" {
+		t.Fatal("expected the stack as its own field")
+	}
+}
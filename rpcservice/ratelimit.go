@@ -0,0 +1,125 @@
+package rpcservice
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/auth"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/logger"
+)
+
+// Limiter decides whether a request may proceed, so rate limits can be backed by memory or a shared store
+type Limiter interface {
+	// Allow reports whether a request for key may proceed, and if not, how long to wait before retrying
+	Allow(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// WithRateLimit limits invocations of the method per caller, keyed by the subject of the identity claims,
+// or the source IP for anonymous requests
+func (m *Method) WithRateLimit(limiter Limiter) *Method {
+	return m.apply(func(m *Method) { m.Limiter = limiter })
+}
+
+// checkRateLimit returns a rate_limited error and sets Retry-After when the caller has exceeded the method's limit.
+// If the limiter itself fails the request is allowed, so an unavailable store does not take the method down.
+func (m *Method) checkRateLimit(ctx context.Context) error {
+	if m.Limiter == nil {
+		return nil
+	}
+
+	allowed, retryAfter, err := m.Limiter.Allow(ctx, m.Name+":"+rateLimitKey(ctx))
+	if err != nil {
+		if reqLogger := logger.FromContext(ctx); reqLogger != nil {
+			reqLogger.Entry().WithError(err).Warn("rpc rate limiter failed")
+		}
+		return nil
+	}
+	if allowed {
+		return nil
+	}
+
+	runtime.SetRetryAfter(ctx, retryAfter)
+
+	return hand.New(runtime.ErrCodeRateLimited)
+}
+
+func rateLimitKey(ctx context.Context) string {
+	if claims, ok := auth.GetIdentityContext(ctx); ok && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+	if meta, ok := runtime.RequestMetaFromContext(ctx); ok && meta.SourceIP != "" {
+		return "ip:" + meta.SourceIP
+	}
+
+	return "anonymous"
+}
+
+// MemoryLimiter is a token bucket Limiter held in memory, so limits apply per process
+type MemoryLimiter struct {
+	rate    float64
+	burst   float64
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// maxIdleBuckets is the number of buckets held before refilled buckets are discarded
+const maxIdleBuckets = 10000
+
+// NewMemoryLimiter creates a MemoryLimiter which allows rate requests per second per key, with bursts of up to burst requests
+func NewMemoryLimiter(rate float64, burst int) *MemoryLimiter {
+	return &MemoryLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// Allow implements Limiter
+func (l *MemoryLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	if len(l.buckets) > maxIdleBuckets {
+		for k, b := range l.buckets {
+			if l.refill(b, now) >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = l.refill(b, now)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	if l.rate <= 0 {
+		return false, time.Minute, nil
+	}
+
+	wait := time.Duration(math.Ceil((1 - b.tokens) / l.rate * float64(time.Second)))
+	return false, wait, nil
+}
+
+func (l *MemoryLimiter) refill(b *tokenBucket, now time.Time) float64 {
+	return math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+}
@@ -0,0 +1,78 @@
+package rpcservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+)
+
+const secretMessage = "connecting to db.internal:5432 failed"
+
+func failingProvider(ctx context.Context) (context.Context, error) {
+	return ctx, hand.New(runtime.ErrCodeUnknown).WithMessage(secretMessage)
+}
+
+func plainFailingProvider(ctx context.Context) (context.Context, error) {
+	return ctx, errors.New(secretMessage)
+}
+
+func TestProviderErrorsRedacted(t *testing.T) {
+	providers := map[string]FallibleContextProvider{
+		"hand error":  failingProvider,
+		"plain error": plainFailingProvider,
+	}
+
+	for name, provider := range providers {
+		t.Run(name, func(t *testing.T) {
+			svc := NewService(testLogger()).
+				WithFallibleContextProvider(provider).
+				AddMethod("$connect", noop, nil).
+				AddMethod("noop", noop, nil)
+
+			httpRes, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("noop", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wsRes, err := svc.WrapAPIGatewayWebsocket()(context.Background(), websocketEvent("$connect", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rpcRes := svc.HandleJSONRPC(testContext(), []byte(`{"jsonrpc": "2.0", "id": 1, "method": "noop"}`), false)
+
+			bodies := map[string]string{
+				"http":      httpRes.Body,
+				"websocket": wsRes.Body,
+				"jsonrpc":   string(rpcRes),
+			}
+			for transport, body := range bodies {
+				if !strings.Contains(body, runtime.ErrCodeUnknown) {
+					t.Errorf("%s: expected the error code in the response, got %s", transport, body)
+				}
+				if strings.Contains(body, secretMessage) {
+					t.Errorf("%s: expected the server error message to be redacted, got %s", transport, body)
+				}
+			}
+		})
+	}
+}
+
+func TestProviderErrorsShownInDevelopment(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithEnvironment(EnvironmentDevelopment).
+		WithFallibleContextProvider(failingProvider).
+		AddMethod("noop", noop, nil)
+
+	res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("noop", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(res.Body, secretMessage) {
+		t.Fatalf("expected the message in development, got %s", res.Body)
+	}
+}
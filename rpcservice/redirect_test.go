@@ -0,0 +1,26 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+)
+
+func oauthCallback(ctx context.Context) (*runtime.Redirect, error) {
+	return &runtime.Redirect{URL: "https://app.example.com/signed-in", Status: http.StatusSeeOther}, nil
+}
+
+func TestRedirectResponse(t *testing.T) {
+	svc := NewService(testLogger()).AddMethod("callback", oauthCallback, nil)
+
+	res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("callback", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != http.StatusSeeOther || res.Headers["Location"] != "https://app.example.com/signed-in" || res.Body != "" {
+		t.Fatalf("expected a 303 redirect without a body, got %d %v: %s", res.StatusCode, res.Headers, res.Body)
+	}
+}
@@ -0,0 +1,60 @@
+package rpcservice
+
+import (
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/g-wilson/runtime/schema"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+var jsonLoaderType = reflect.TypeOf((*gojsonschema.JSONLoader)(nil)).Elem()
+
+// Register adds every exported method of obj whose first argument is a context as an RPC method.
+// Method names have their first letter lowercased, so GetUser is served as getUser.
+// The schema for a method is taken from a GetUserSchema() gojsonschema.JSONLoader method if obj has one,
+// otherwise it is derived from the request type using the schema package.
+// Methods which take a context but are not valid handlers cause a panic, as with AddMethod.
+//
+//	type App struct{}
+//
+//	func (a *App) GetUser(ctx context.Context, req *GetUserRequest) (*User, error)
+//	func (a *App) GetUserSchema() gojsonschema.JSONLoader
+//
+//	svc.Register(&App{})
+func (s *Service) Register(obj interface{}) *Service {
+	v := reflect.ValueOf(obj)
+	t := v.Type()
+
+	for i := 0; i < t.NumMethod(); i++ {
+		handler := v.Method(i)
+		handlerType := handler.Type()
+
+		if handlerType.NumIn() == 0 || !handlerType.In(0).Implements(contextType) {
+			continue
+		}
+
+		name := t.Method(i).Name
+
+		var sc gojsonschema.JSONLoader
+		if schemaMethod := v.MethodByName(name + "Schema"); schemaMethod.IsValid() &&
+			schemaMethod.Type().NumIn() == 0 &&
+			schemaMethod.Type().NumOut() == 1 &&
+			schemaMethod.Type().Out(0) == jsonLoaderType {
+			sc = schemaMethod.Call(nil)[0].Interface().(gojsonschema.JSONLoader)
+		} else if handlerType.NumIn() == 2 {
+			sc = schema.FromStruct(reflect.New(handlerType.In(1)).Elem().Interface())
+		}
+
+		s.AddMethod(lowerFirst(name), handler.Interface(), sc)
+	}
+
+	return s
+}
+
+func lowerFirst(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
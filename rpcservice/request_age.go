@@ -0,0 +1,44 @@
+package rpcservice
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+)
+
+// WithMaxRequestAge rejects requests whose timestamp header, in unix seconds or RFC 3339, is older than maxAge or
+// further than skew in the future, to mitigate replay of signed requests. Requests without the header are rejected.
+func (s *Service) WithMaxRequestAge(header string, maxAge, skew time.Duration) *Service {
+	s.RequestMetaHeaders = append(s.RequestMetaHeaders, header)
+
+	return s.WithFallibleContextProvider(func(ctx context.Context) (context.Context, error) {
+		return ctx, checkRequestAge(runtime.RequestHeader(ctx, header), time.Now(), maxAge, skew)
+	})
+}
+
+func checkRequestAge(value string, now time.Time, maxAge, skew time.Duration) error {
+	if value == "" {
+		return hand.New(runtime.ErrCodeBadRequest).WithMessage("request timestamp missing")
+	}
+
+	var ts time.Time
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		ts = time.Unix(secs, 0)
+	} else if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		ts = parsed
+	} else {
+		return hand.New(runtime.ErrCodeBadRequest).WithMessage("request timestamp invalid")
+	}
+
+	if ts.Before(now.Add(-maxAge)) {
+		return hand.New(runtime.ErrCodeBadRequest).WithMessage("request timestamp too old")
+	}
+	if ts.After(now.Add(skew)) {
+		return hand.New(runtime.ErrCodeBadRequest).WithMessage("request timestamp in the future")
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+)
+
+func TestCheckRequestAge(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	unix := func(d time.Duration) string { return strconv.FormatInt(now.Add(d).Unix(), 10) }
+
+	tests := []struct {
+		name    string
+		value   string
+		message string
+	}{
+		{"current unix", unix(0), ""},
+		{"current rfc 3339", now.Add(-time.Minute).Format(time.RFC3339), ""},
+		{"within skew", unix(20 * time.Second), ""},
+		{"missing", "", "request timestamp missing"},
+		{"invalid", "yesterday", "request timestamp invalid"},
+		{"too old", unix(-10 * time.Minute), "request timestamp too old"},
+		{"in the future", unix(time.Minute), "request timestamp in the future"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRequestAge(tt.value, now, 5*time.Minute, 30*time.Second)
+			if tt.message == "" {
+				if err != nil {
+					t.Fatalf("expected request to be accepted, got %v", err)
+				}
+				return
+			}
+			handErr, ok := err.(hand.E)
+			if !ok || handErr.Code != runtime.ErrCodeBadRequest || handErr.Message != tt.message {
+				t.Fatalf("expected bad_request %q, got %#v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestMaxRequestAge(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithMaxRequestAge("X-Timestamp", 5*time.Minute, 30*time.Second).
+		AddMethod("noop", noop, nil)
+
+	for value, status := range map[string]int{
+		strconv.FormatInt(time.Now().Unix(), 10):                 http.StatusNoContent,
+		strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10): http.StatusBadRequest,
+		"": http.StatusBadRequest,
+	} {
+		event := httpEvent("noop", nil)
+		event.Headers = map[string]string{"x-timestamp": value}
+
+		res, err := svc.WrapAPIGatewayHTTP()(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != status {
+			t.Fatalf("timestamp %q: expected %d, got %d: %s", value, status, res.StatusCode, res.Body)
+		}
+	}
+}
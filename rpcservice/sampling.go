@@ -0,0 +1,30 @@
+package rpcservice
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/g-wilson/runtime/internal/ctxkey"
+)
+
+var sampledOutKey = ctxkey.New("sampledout")
+
+// WithLogSampling logs only 1 in n successful invocations of the method, to reduce log volume for chatty methods.
+// Errors are always logged. A rate of 1 or less logs every invocation.
+func (m *Method) WithLogSampling(n int) *Method {
+	return m.apply(func(m *Method) { m.LogSampleRate = n })
+}
+
+// sampleLog reports whether the success logs of the next invocation should be written
+func (m *Method) sampleLog() bool {
+	if m.LogSampleRate <= 1 {
+		return true
+	}
+
+	return atomic.AddUint32(&m.sampleCount, 1)%uint32(m.LogSampleRate) == 1
+}
+
+func isSampledOut(ctx context.Context) bool {
+	sampledOut, _ := ctx.Value(sampledOutKey).(bool)
+	return sampledOut
+}
@@ -0,0 +1,87 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/logger"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func poll(ctx context.Context, body *json.RawMessage) error {
+	if string(*body) == `"fail"` {
+		return hand.New(runtime.ErrCodeBadRequest)
+	}
+	return nil
+}
+
+func TestLogSampling(t *testing.T) {
+	log, hook := test.NewNullLogger()
+
+	svc := NewService(logrus.NewEntry(log)).AddMethodWithoutValidation("poll", poll)
+	method, _ := svc.GetMethod("poll")
+	method.WithLogSampling(10)
+
+	var failures int
+	for i := 0; i < 200; i++ {
+		body := []byte(`"ok"`)
+		if i%7 == 0 {
+			body = []byte(`"fail"`)
+			failures++
+		}
+
+		ctx := logger.SetContext(context.Background(), logrus.NewEntry(log))
+		svc.Invoke(ctx, method, body)
+	}
+
+	var successes, errors int
+	for _, entry := range hook.AllEntries() {
+		if entry.Message != "rpc request completed" {
+			continue
+		}
+		if entry.Data["code"] == "ok" {
+			successes++
+		} else {
+			errors++
+		}
+	}
+
+	// 1 in 10 invocations is sampled, so roughly a tenth of the successful ones are logged
+	if successes < 10 || successes > 25 {
+		t.Errorf("expected about 1 in 10 of %d successes to be logged, got %d", 200-failures, successes)
+	}
+	if errors != failures {
+		t.Errorf("expected all %d errors to be logged, got %d", failures, errors)
+	}
+}
+
+func TestLogSamplingDisabled(t *testing.T) {
+	for _, rate := range []int{0, 1} {
+		m := &Method{LogSampleRate: rate}
+		for i := 0; i < 5; i++ {
+			if !m.sampleLog() {
+				t.Fatalf("rate %d: expected every invocation to be logged", rate)
+			}
+		}
+	}
+}
+
+func TestLogSamplingIsExactlyOneInN(t *testing.T) {
+	m := &Method{LogSampleRate: 4}
+
+	var sampled int
+	for i := 0; i < 100; i++ {
+		if m.sampleLog() {
+			sampled++
+		}
+	}
+
+	if sampled != 25 {
+		t.Fatalf("expected 25 of 100 invocations to be sampled, got %d", sampled)
+	}
+}
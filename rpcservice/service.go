@@ -2,8 +2,19 @@ package rpcservice
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/internal/stacktrace"
+	"github.com/g-wilson/runtime/logger"
 
 	"github.com/sirupsen/logrus"
 	"github.com/xeipuuv/gojsonschema"
@@ -11,24 +22,97 @@ import (
 
 var errorType = reflect.TypeOf((*error)(nil)).Elem()
 var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+var methodNamePattern = regexp.MustCompile(`^\$?[A-Za-z0-9._\-/]{1,128}$`)
+
+const (
+	// EnvironmentDevelopment includes the message and meta of server errors in responses
+	EnvironmentDevelopment = "development"
+	// EnvironmentStaging redacts server errors in responses, as in production
+	EnvironmentStaging = "staging"
+	// EnvironmentProduction redacts server errors in responses to their code
+	EnvironmentProduction = "production"
+)
+
+// DefaultNamespaceSeparator separates the segments of namespaced method names in their canonical form, as in users.create
+const DefaultNamespaceSeparator = "."
 
 // ContextProvider is a function which is called before the request
 type ContextProvider func(ctx context.Context) context.Context
 
+// FallibleContextProvider is a function which is called before the request and can abort it by returning an error
+type FallibleContextProvider func(ctx context.Context) (context.Context, error)
+
 // IdentityContextProvider is a special context provider which has an argument for the access token claims of the current request
 type IdentityContextProvider func(ctx context.Context, claims map[string]interface{}) context.Context
 
+// FallbackHandler handles requests for methods which are not registered on the service
+type FallbackHandler func(ctx context.Context, methodName string, body []byte) (interface{}, error)
+
 // Service encapsulates an instance of an RPC Service
 type Service struct {
-	Logger           *logrus.Entry
-	Methods          map[string]*Method
-	ContextProviders []ContextProvider
-	IdentityProvider IdentityContextProvider
+	Name                  string
+	Logger                *logrus.Entry
+	Methods               map[string]*Method
+	ContextProviders      []ContextProvider
+	FallibleProviders     []FallibleContextProvider
+	IdentityProvider      IdentityContextProvider
+	TenantExtractor       TenantExtractor
+	RequireAuthentication bool
+	FailClosed            bool
+	DisableAccessLog      bool
+	MaxRequestDepth       int
+	MaxRequestTokens      int
+	MaxRequestBytes       int
+	MaxResponseBytes      int
+	RequestMetaHeaders    []string
+	ClientErrorLogLevel   logrus.Level
+	ServerErrorLogLevel   logrus.Level
+	MethodNameNormalizer  func(string) string
+	NamespaceSeparator    string
+	MinTimeRemaining      time.Duration
+	DisallowUnknownFields bool
+	UseNumber             bool
+	Validator             Validator
+	EnableMsgpack         bool
+	EnableEnvelope        bool
+	ShutdownHooks         []func(ctx context.Context) error
+	Environment           string
+	Fallback              FallbackHandler
+	EnableStats           bool
+	SharedSchemas         map[string]gojsonschema.JSONLoader
+	WarmupDetector        WarmupDetector
+	Warmer                func(ctx context.Context) error
+	RequestVerifiers      []RequestVerifier
+	PanicStackDepth       int
+	Auditor               Auditor
+	stats                 stats
+	schemaErrors          []string
 }
 
 // NewService creates a Service
 func NewService(l *logrus.Entry) *Service {
-	return &Service{Logger: l, Methods: make(map[string]*Method)}
+	return &Service{
+		Logger:           l,
+		Methods:          make(map[string]*Method),
+		MaxRequestDepth:  DefaultMaxRequestDepth,
+		MaxRequestTokens: DefaultMaxRequestTokens,
+		MaxRequestBytes:  DefaultMaxRequestBytes,
+		MaxResponseBytes: DefaultMaxResponseBytes,
+		Environment:      EnvironmentProduction,
+
+		NamespaceSeparator: DefaultNamespaceSeparator,
+
+		ClientErrorLogLevel: logrus.WarnLevel,
+		ServerErrorLogLevel: logrus.ErrorLevel,
+	}
+}
+
+// WithName names the service and adds it to every log entry under logger.ServiceNameKey
+func (s *Service) WithName(name string) *Service {
+	s.Name = name
+	s.Logger = s.Logger.WithField(logger.ServiceNameKey, name)
+	return s
 }
 
 // WithIdentityProvider attaches an identity provider function to the service
@@ -37,42 +121,492 @@ func (s *Service) WithIdentityProvider(idp IdentityContextProvider) *Service {
 	return s
 }
 
+// WithRequireAuthentication rejects unauthenticated requests to methods which do not allow anonymous access
+func (s *Service) WithRequireAuthentication() *Service {
+	s.RequireAuthentication = true
+	return s
+}
+
+// WithFailClosed rejects requests without an identity whenever an identity provider or tenant extractor is configured,
+// so a missing or misconfigured gateway authorizer cannot expose methods publicly. Methods which allow anonymous
+// access are unaffected.
+func (s *Service) WithFailClosed() *Service {
+	s.FailClosed = true
+	return s
+}
+
+// RequiresAuthentication reports whether requests to the method must carry an identity
+func (s *Service) RequiresAuthentication(m *Method) bool {
+	if m.AllowAnonymous {
+		return false
+	}
+
+	return s.RequireAuthentication || (s.FailClosed && s.UsesIdentity())
+}
+
+// WithPanicStackDepth limits the number of stack frames logged when a handler panics. Stacks are never sent to clients.
+func (s *Service) WithPanicStackDepth(frames int) *Service {
+	s.PanicStackDepth = frames
+	return s
+}
+
+func (s *Service) panicStackDepth() int {
+	if s == nil {
+		return stacktrace.DefaultMaxDepth
+	}
+	return s.PanicStackDepth
+}
+
+// WithRequestLimits overrides the maximum JSON nesting depth and token count accepted in request bodies
+func (s *Service) WithRequestLimits(maxDepth, maxTokens int) *Service {
+	s.MaxRequestDepth = maxDepth
+	s.MaxRequestTokens = maxTokens
+	return s
+}
+
+// WithEnvironment sets the environment the service runs in. Outside of development, the message and meta of errors
+// with a 5xx status are removed from responses so internal details are not leaked; client errors are always shown.
+func (s *Service) WithEnvironment(env string) *Service {
+	s.Environment = env
+	return s
+}
+
+// WithMaxRequestBytes overrides the limit on the size of decoded request bodies received from API Gateway
+func (s *Service) WithMaxRequestBytes(maxBytes int) *Service {
+	s.MaxRequestBytes = maxBytes
+	return s
+}
+
+// WithMaxResponseBytes overrides the limit on the size of encoded response bodies
+func (s *Service) WithMaxResponseBytes(maxBytes int) *Service {
+	s.MaxResponseBytes = maxBytes
+	return s
+}
+
+// WithRequestMetaHeaders allowlists request headers which are made available to handlers through runtime.RequestMetaFromContext
+func (s *Service) WithRequestMetaHeaders(names ...string) *Service {
+	s.RequestMetaHeaders = append(s.RequestMetaHeaders, names...)
+	return s
+}
+
+// WithErrorLogLevels sets the levels at which errors mapping to 4xx and 5xx statuses are logged
+func (s *Service) WithErrorLogLevels(clientErrors, serverErrors logrus.Level) *Service {
+	s.ClientErrorLogLevel = clientErrors
+	s.ServerErrorLogLevel = serverErrors
+	return s
+}
+
+// ErrorLogLevel returns the level at which an error code should be logged, based on the HTTP status it maps to
+func (s *Service) ErrorLogLevel(code string) logrus.Level {
+	if runtime.HTTPStatus(code) < http.StatusInternalServerError {
+		if s == nil || s.ClientErrorLogLevel == logrus.PanicLevel {
+			return logrus.WarnLevel
+		}
+		return s.ClientErrorLogLevel
+	}
+
+	if s == nil || s.ServerErrorLogLevel == logrus.PanicLevel {
+		return logrus.ErrorLevel
+	}
+	return s.ServerErrorLogLevel
+}
+
+// WithNamespaceSeparator changes the separator of namespaced method names from the default "."
+func (s *Service) WithNamespaceSeparator(sep string) *Service {
+	s.NamespaceSeparator = sep
+	return s
+}
+
+// WithMethodNameNormalizer applies a function to method names at registration and lookup, so that differently
+// formatted names (such as get-user and getUser) resolve to the same method. It must be set before adding methods.
+func (s *Service) WithMethodNameNormalizer(fn func(string) string) *Service {
+	s.MethodNameNormalizer = fn
+	return s
+}
+
+// WithMinTimeRemaining makes the Lambda wrappers reject requests with a retryable error when less than d remains
+// before the execution deadline, rather than starting work which would be killed part way through
+func (s *Service) WithMinTimeRemaining(d time.Duration) *Service {
+	s.MinTimeRemaining = d
+	return s
+}
+
+// checkTimeRemaining returns an error if the context deadline is too close to start handling a request
+func (s *Service) checkTimeRemaining(ctx context.Context) error {
+	if s.MinTimeRemaining <= 0 {
+		return nil
+	}
+
+	remaining := runtime.TimeRemaining(ctx)
+	if remaining >= s.MinTimeRemaining {
+		return nil
+	}
+
+	return hand.New(runtime.ErrCodeInsufficientTime).WithMeta(hand.M{"retryable": true, "remaining_ms": remaining.Milliseconds()})
+}
+
+// WithDisallowUnknownFields rejects request bodies containing fields which the handler's request type does not declare
+func (s *Service) WithDisallowUnknownFields() *Service {
+	s.DisallowUnknownFields = true
+	return s
+}
+
+// WithUseNumber decodes numbers in request fields of type interface{} as json.Number instead of float64, so that
+// large integers and decimals such as monetary amounts keep their precision. Schema validation always keeps precision.
+func (s *Service) WithUseNumber() *Service {
+	s.UseNumber = true
+	return s
+}
+
+// WithValidator replaces the JSON Schema validator used by the service. It must be set before adding methods.
+func (s *Service) WithValidator(v Validator) *Service {
+	s.Validator = v
+	return s
+}
+
+// WithSharedSchema registers a schema which method schemas can reference with {"$ref": url}, such as a common type
+// definition. Shared schemas must be registered before adding the methods which use them.
+func (s *Service) WithSharedSchema(url string, schema gojsonschema.JSONLoader) *Service {
+	if s.SharedSchemas == nil {
+		s.SharedSchemas = map[string]gojsonschema.JSONLoader{}
+	}
+	s.SharedSchemas[url] = schema
+	return s
+}
+
+// compileSchema compiles a method schema with the service's validator, resolving references to shared schemas
+func (s *Service) compileSchema(schema gojsonschema.JSONLoader) (CompiledSchema, error) {
+	if len(s.SharedSchemas) == 0 {
+		return s.validator().Compile(schema)
+	}
+
+	resolver, ok := s.validator().(RefResolver)
+	if !ok {
+		return nil, fmt.Errorf("validator %T cannot resolve shared schemas", s.validator())
+	}
+
+	return resolver.CompileWithRefs(schema, s.SharedSchemas)
+}
+
+func (s *Service) validator() Validator {
+	if s == nil || s.Validator == nil {
+		return GoJSONSchemaValidator{}
+	}
+	return s.Validator
+}
+
+// WithMsgpack allows requests and responses to be encoded as MessagePack, negotiated by the Content-Type and Accept headers
+func (s *Service) WithMsgpack() *Service {
+	s.EnableMsgpack = true
+	return s
+}
+
+// WithoutAccessLog disables the completion log entry written after every method invocation
+func (s *Service) WithoutAccessLog() *Service {
+	s.DisableAccessLog = true
+	return s
+}
+
 // WithContextProvider attaches a callback function to the request hooks where context can be modified
 func (s *Service) WithContextProvider(handler ContextProvider) *Service {
 	s.ContextProviders = append(s.ContextProviders, handler)
 	return s
 }
 
-// AddMethod creates a Method and adds it to the service
+// WithConfig adds application config to the context of every request, retrieved by handlers with runtime.ConfigFromContext
+func (s *Service) WithConfig(cfg interface{}) *Service {
+	return s.WithContextProvider(func(ctx context.Context) context.Context {
+		return runtime.WithConfig(ctx, cfg)
+	})
+}
+
+// WithFallibleContextProvider attaches a callback function to the request hooks which can modify context or abort the request.
+// A non-hand error is reported to the client as unknown.
+func (s *Service) WithFallibleContextProvider(handler FallibleContextProvider) *Service {
+	s.FallibleProviders = append(s.FallibleProviders, handler)
+	return s
+}
+
+// ProvideContext runs the context providers for a request. It is called after the identity provider and before
+// schema validation. Plain context providers run first in the order they were added, then fallible providers in
+// the order they were added, stopping at the first error.
+func (s *Service) ProvideContext(ctx context.Context) (context.Context, error) {
+	for _, fn := range s.ContextProviders {
+		ctx = fn(ctx)
+	}
+
+	for _, fn := range s.FallibleProviders {
+		var err error
+		ctx, err = fn(ctx)
+		if err != nil {
+			if handErr, ok := err.(hand.E); ok {
+				return ctx, handErr
+			}
+			return ctx, hand.Wrap(runtime.ErrCodeUnknown, err)
+		}
+	}
+
+	return ctx, nil
+}
+
+// OnShutdown registers a function to release resources held by the service when it shuts down
+func (s *Service) OnShutdown(fn func(ctx context.Context) error) *Service {
+	s.ShutdownHooks = append(s.ShutdownHooks, fn)
+	return s
+}
+
+// Shutdown runs the shutdown hooks in the reverse order they were registered, returning all errors encountered
+func (s *Service) Shutdown(ctx context.Context) error {
+	var problems []string
+
+	for i := len(s.ShutdownHooks) - 1; i >= 0; i-- {
+		if err := s.ShutdownHooks[i](ctx); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("runtime service shutdown failed: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// AddMethod creates a Method and adds it to the service. A schema which cannot be compiled is reported by Validate.
 func (s *Service) AddMethod(methodName string, handler interface{}, schema gojsonschema.JSONLoader) *Service {
 	method := &Method{
 		Name:    methodName,
 		Handler: handler,
+		service: s,
 	}
 
 	if schema != nil {
-		sc, err := gojsonschema.NewSchema(schema)
-		if err != nil {
-			panic(fmt.Errorf("runtime cannot parse schema for method %s: %w", methodName, err))
+		sc, ok := s.registerSchema(methodName, schema)
+		if !ok {
+			return s
 		}
 
 		method.CompiledSchema = sc
 	}
 
+	return s.addMethod(method)
+}
+
+// registerSchema compiles the schema of a method being added. A schema which cannot be compiled is recorded for
+// Validate to report, and the method is not added.
+func (s *Service) registerSchema(description string, schema gojsonschema.JSONLoader) (CompiledSchema, bool) {
+	sc, err := s.compileSchema(schema)
+	if err != nil {
+		s.schemaErrors = append(s.schemaErrors, fmt.Sprintf("%s: schema cannot be compiled: %s", description, err))
+		return nil, false
+	}
+
+	return sc, true
+}
+
+func (s *Service) addMethod(method *Method) *Service {
+	if !methodNamePattern.MatchString(method.Name) {
+		panic(fmt.Errorf("runtime cannot add rpc method %q: name must be 1 to 128 letters, digits, or . _ - / characters, optionally prefixed with $ for websocket routes", method.Name))
+	}
+
+	key := s.normalizeMethodName(method.Name)
+	if existing, ok := s.Methods[key]; ok {
+		panic(fmt.Errorf("runtime cannot add rpc method %s: name conflicts with method %s", method.Name, existing.Name))
+	}
+
 	hasReqBody, hasResBody, err := validateMethod(method)
 	if err != nil {
-		panic(fmt.Errorf("runtime cannot add rpc method %s: %w", methodName, err))
+		panic(fmt.Errorf("runtime cannot add rpc method %s: %w", method.Name, err))
 	}
 
 	method.expectsRequestBody = hasReqBody
 	method.expectsResponseBody = hasResBody
 
-	s.Methods[methodName] = method
+	s.Methods[key] = method
 	return s
 }
 
-// GetMethod finds an attached Method by name
+// AddMethodWithoutValidation creates a Method which accepts any JSON request body and adds it to the service.
+// The handler is responsible for validating the request, which may be a struct or *json.RawMessage to receive the body untouched.
+func (s *Service) AddMethodWithoutValidation(methodName string, handler interface{}) *Service {
+	return s.addMethod(&Method{
+		Name:              methodName,
+		Handler:           handler,
+		WithoutValidation: true,
+		service:           s,
+	})
+}
+
+// Validate checks every registered method can be served, returning all problems found. Schemas are compiled
+// eagerly when methods are added, and a method whose schema cannot be compiled is not added but reported here,
+// so services should call Validate from main or a test to fail at startup rather than on the first request.
+func (s *Service) Validate() error {
+	problems := append([]string{}, s.schemaErrors...)
+
+	for _, name := range s.MethodNames() {
+		method := s.Methods[name]
+
+		if _, _, err := validateMethod(method); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", method.Name, err))
+			continue
+		}
+
+		if method.CompiledSchema == nil {
+			continue
+		}
+
+		// a compiled schema should always be able to run; anything but a schema violation means it cannot
+		err := s.validator().Validate(method.CompiledSchema, []byte("{}"))
+		if _, isSchemaErr := err.(*SchemaError); err != nil && !isSchemaErr {
+			problems = append(problems, fmt.Sprintf("%s: schema cannot be evaluated: %s", method.Name, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("runtime service has invalid methods: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// MethodNames returns the names under which methods are registered, in sorted order
+func (s *Service) MethodNames() []string {
+	names := make([]string, 0, len(s.Methods))
+	for name := range s.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// HasMethod returns true if a method is registered under the name, after normalisation
+func (s *Service) HasMethod(methodName string) bool {
+	_, ok := s.Methods[s.normalizeMethodName(methodName)]
+	return ok
+}
+
+// GetMethod finds an attached Method by name. When no method is registered under the name and the service has a
+// fallback, a Method which invokes the fallback is returned instead.
 func (s *Service) GetMethod(methodName string) (*Method, bool) {
-	m, ok := s.Methods[methodName]
+	m, ok := s.Methods[s.normalizeMethodName(methodName)]
+	if !ok && s.Fallback != nil {
+		return &Method{
+			Name:              methodName,
+			Handler:           s.Fallback,
+			WithoutValidation: true,
+			service:           s,
+			isFallback:        true,
+		}, true
+	}
+
 	return m, ok
 }
+
+// SetFallback sets a handler which is invoked with the method name and raw body of requests for unregistered methods,
+// instead of responding with method_not_found
+func (s *Service) SetFallback(handler FallbackHandler) *Service {
+	s.Fallback = handler
+	return s
+}
+
+// normalizeMethodName converts a method name into its canonical form, in which namespace segments given as path
+// segments, such as users/create, are joined by the namespace separator, as in users.create. Trailing slashes are
+// left in place, so they only resolve where a transport is configured to strip them.
+func (s *Service) normalizeMethodName(methodName string) string {
+	sep := s.NamespaceSeparator
+	if sep == "" {
+		sep = DefaultNamespaceSeparator
+	}
+	methodName = strings.ReplaceAll(strings.TrimPrefix(methodName, "/"), "/", sep)
+
+	if s.MethodNameNormalizer == nil {
+		return methodName
+	}
+	return s.MethodNameNormalizer(methodName)
+}
+
+// NormalizeMethodName is a method name normalizer which ignores case, hyphens and underscores
+func NormalizeMethodName(methodName string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(methodName))
+}
+
+// Invoke executes a method and writes a completion log entry with its duration and result code
+func (s *Service) Invoke(ctx context.Context, m *Method, body []byte) (interface{}, error) {
+	startedAt := time.Now()
+
+	var result interface{}
+
+	if m.Idempotent {
+		runtime.SetResponseHeader(ctx, "X-Idempotent", "true")
+	}
+
+	sampled := m.sampleLog()
+	if !sampled {
+		ctx = context.WithValue(ctx, sampledOutKey, true)
+	}
+
+	if s.audits(m) {
+		ctx = runtime.WithAudit(ctx)
+	}
+
+	var err error
+	if !m.NDJSON {
+		// NDJSON records are limited individually as they are decoded, a body is a sequence of records not a document
+		err = checkRequestLimits(body, s.MaxRequestDepth, s.MaxRequestTokens)
+	}
+	if err == nil {
+		err = m.checkRateLimit(ctx)
+	}
+	if err == nil {
+		result, err = m.Invoke(ctx, body)
+	}
+	if err == nil && s.audits(m) {
+		s.audit(ctx, m, startedAt)
+	}
+
+	s.recordStats(m, err)
+
+	if !s.DisableAccessLog && (sampled || err != nil) {
+		code, level := "ok", logrus.InfoLevel
+		if err != nil {
+			code = runtime.ErrCodeUnknown
+			if handErr, ok := err.(hand.E); ok {
+				code = handErr.Code
+			}
+			level = s.ErrorLogLevel(code)
+		}
+
+		entry := s.Logger
+		if reqLogger := logger.FromContext(ctx); reqLogger != nil {
+			entry = reqLogger.Entry()
+		}
+
+		if logEnabled(entry, level) {
+			fields := getFields()
+			fields["method"] = m.Name
+			fields["duration_ms"] = float64(time.Since(startedAt).Microseconds()) / 1000
+			fields["code"] = code
+			fields["request_bytes"] = len(body)
+			if m.CompiledSchema != nil {
+				fields["schema_valid"] = code != runtime.ErrCodeSchemaFailure
+			}
+
+			entry.WithFields(fields).Log(level, "rpc request completed")
+			putFields(fields)
+		}
+	}
+
+	return result, s.RedactError(err)
+}
+
+// RedactError removes the message and meta from server errors unless the service runs in development.
+// Errors returned by Invoke are already redacted; execution environments apply it to errors raised around Invoke.
+func (s *Service) RedactError(err error) error {
+	handErr, ok := err.(hand.E)
+	if !ok || s.Environment == EnvironmentDevelopment || runtime.HTTPStatus(handErr.Code) < http.StatusInternalServerError {
+		return err
+	}
+
+	return hand.E{Code: handErr.Code, Err: handErr.Err, LogMeta: handErr.LogMeta}
+}
@@ -0,0 +1,182 @@
+package rpcservice
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime/logger"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func testLogger() *logrus.Entry {
+	l := logrus.New()
+	l.Out = ioutil.Discard
+
+	return logrus.NewEntry(l)
+}
+
+// testContext returns a context carrying a request logger, as the execution environments provide
+func testContext() context.Context {
+	return logger.SetContext(context.Background(), testLogger())
+}
+
+func noop(ctx context.Context) error {
+	return nil
+}
+
+func TestWithNameAddsServiceField(t *testing.T) {
+	log, hook := test.NewNullLogger()
+
+	svc := NewService(logrus.NewEntry(log).WithField(logger.ServiceKey, "api")).
+		WithName("billing").
+		WithName("users").
+		AddMethod("noop", noop, nil)
+
+	if _, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("noop", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	var completed bool
+	for _, entry := range hook.AllEntries() {
+		if entry.Data[logger.ServiceNameKey] != "users" {
+			t.Fatalf("expected every entry to carry the service name, got %v", entry.Data)
+		}
+		if entry.Data[logger.ServiceKey] != "api" {
+			t.Fatalf("expected the process name to be kept, got %v", entry.Data)
+		}
+		if entry.Message == "rpc request completed" {
+			completed = true
+		}
+	}
+	if !completed {
+		t.Fatal("expected a completion log entry")
+	}
+}
+
+func TestValidateReportsSchemaCompileErrors(t *testing.T) {
+	svc := NewService(testLogger()).
+		AddMethod("valid", echo, echoSchema).
+		AddMethod("malformed", echo, gojsonschema.NewStringLoader(`{"type": 42}`)).
+		AddMethodVersion("valid", "2", echo, gojsonschema.NewStringLoader(`{"type": "object"`))
+
+	err := svc.Validate()
+	if err == nil {
+		t.Fatal("expected the invalid schemas to be reported")
+	}
+	for _, name := range []string{"malformed", "valid version 2"} {
+		if !strings.Contains(err.Error(), name+": schema cannot be compiled") {
+			t.Errorf("expected an error for %s, got %v", name, err)
+		}
+	}
+
+	if svc.HasMethod("malformed") {
+		t.Error("expected a method with an invalid schema not to be added")
+	}
+	if !svc.HasMethod("valid") {
+		t.Error("expected the valid method to be added")
+	}
+}
+
+func TestValidateAcceptsValidService(t *testing.T) {
+	svc := NewService(testLogger()).
+		AddMethod("echo", echo, echoSchema).
+		AddMethod("noop", noop, nil)
+
+	if err := svc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMethodNamesOrderIsStable(t *testing.T) {
+	names := []string{"users.get", "accounts.list", "ping", "accounts.create", "zeta"}
+	expected := []string{"accounts.create", "accounts.list", "ping", "users.get", "zeta"}
+
+	for _, order := range [][]int{{0, 1, 2, 3, 4}, {4, 3, 2, 1, 0}, {2, 0, 4, 1, 3}} {
+		svc := NewService(testLogger())
+		for _, i := range order {
+			svc.AddMethod(names[i], noop, nil)
+		}
+
+		got := svc.MethodNames()
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Fatalf("registration order %v: expected %v, got %v", order, expected, got)
+		}
+		if again := svc.MethodNames(); strings.Join(again, ",") != strings.Join(got, ",") {
+			t.Fatalf("expected repeated calls to return the same order, got %v then %v", got, again)
+		}
+	}
+}
+
+func TestHasMethodNormalizesNames(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithMethodNameNormalizer(NormalizeMethodName).
+		AddMethod("users.getProfile", noop, nil)
+
+	for _, name := range []string{"users.getProfile", "/users.getProfile", "users/getProfile", "users.get-profile", "USERS.GET_PROFILE"} {
+		if !svc.HasMethod(name) {
+			t.Errorf("expected %q to match the registered method", name)
+		}
+	}
+
+	for _, name := range []string{"users.getProfiles", "users", ""} {
+		if svc.HasMethod(name) {
+			t.Errorf("expected %q not to match", name)
+		}
+	}
+}
+
+// registrationPanic returns the message of the panic raised by register, or an empty string if it did not panic
+func registrationPanic(register func()) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg = fmt.Sprint(r)
+		}
+	}()
+
+	register()
+	return ""
+}
+
+func TestDuplicateMethodRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		svc    *Service
+		second string
+	}{
+		{"same name", NewService(testLogger()), "users.get"},
+		{"same name after normalisation", NewService(testLogger()).WithMethodNameNormalizer(NormalizeMethodName), "Users.Get"},
+		{"path separator", NewService(testLogger()), "users/get"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.svc.AddMethod("users.get", noop, nil)
+
+			msg := registrationPanic(func() { tt.svc.AddMethod(tt.second, noop, nil) })
+			if !strings.Contains(msg, "conflicts with method users.get") {
+				t.Fatalf("expected a conflict, got %q", msg)
+			}
+		})
+	}
+}
+
+func TestInvalidMethodNameRejected(t *testing.T) {
+	for _, name := range []string{"", "has space", "semi;colon", "$", strings.Repeat("a", 129), "emoji🙂"} {
+		msg := registrationPanic(func() { NewService(testLogger()).AddMethod(name, noop, nil) })
+		if !strings.Contains(msg, "name must be") {
+			t.Errorf("%q: expected the name to be rejected, got %q", name, msg)
+		}
+	}
+
+	for _, name := range []string{"ping", "users.get", "users/get", "get-user_v2", "$connect", strings.Repeat("a", 128)} {
+		if msg := registrationPanic(func() { NewService(testLogger()).AddMethod(name, noop, nil) }); msg != "" {
+			t.Errorf("%q: expected the name to be accepted, got %q", name, msg)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const addressSchemaURL = "https://schemas.example.com/address.json"
+
+var addressSchema = gojsonschema.NewStringLoader(`{
+	"definitions": {
+		"address": {
+			"type": "object",
+			"required": ["line1", "postcode"],
+			"properties": {
+				"line1": {"type": "string"},
+				"postcode": {"type": "string", "minLength": 5}
+			}
+		}
+	}
+}`)
+
+type shippingRequest struct {
+	Address struct {
+		Line1    string `json:"line1"`
+		Postcode string `json:"postcode"`
+	} `json:"address"`
+}
+
+func ship(ctx context.Context, req *shippingRequest) error {
+	return nil
+}
+
+func TestSharedSchemaReference(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithSharedSchema(addressSchemaURL, addressSchema).
+		AddMethod("ship", ship, gojsonschema.NewStringLoader(`{
+			"type": "object",
+			"required": ["address"],
+			"properties": {
+				"address": {"$ref": "`+addressSchemaURL+`#/definitions/address"}
+			}
+		}`))
+
+	if err := svc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"address": {"line1": "1 High Street", "postcode": "AB1 2CD"}}`, http.StatusNoContent},
+		{`{"address": {"line1": "1 High Street", "postcode": "AB"}}`, http.StatusBadRequest},
+		{`{"address": {"line1": "1 High Street"}}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		event := httpEvent("ship", nil)
+		event.Body = tt.body
+
+		res, err := svc.WrapAPIGatewayHTTP()(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != tt.status {
+			t.Fatalf("%s: expected %d, got %d: %s", tt.body, tt.status, res.StatusCode, res.Body)
+		}
+	}
+}
+
+func TestUnresolvableSharedSchemaReference(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithSharedSchema(addressSchemaURL, addressSchema).
+		AddMethod("ship", ship, gojsonschema.NewStringLoader(`{
+			"type": "object",
+			"properties": {
+				"address": {"$ref": "`+addressSchemaURL+`#/definitions/missing"}
+			}
+		}`))
+
+	err := svc.Validate()
+	if err == nil || !strings.Contains(err.Error(), "ship: schema cannot be compiled") {
+		t.Fatalf("expected the unresolvable reference to be reported at registration, got %v", err)
+	}
+	if svc.HasMethod("ship") {
+		t.Fatal("expected the method with an unresolvable reference not to be added")
+	}
+}
@@ -0,0 +1,64 @@
+package rpcservice
+
+import (
+	"sync"
+)
+
+// FallbackStatsName is the name under which all requests handled by the fallback are counted. It cannot be the name of
+// a registered method, and a single entry stops callers growing the stats with arbitrary method names.
+const FallbackStatsName = "(fallback)"
+
+// MethodStats counts the invocations of a method
+type MethodStats struct {
+	Invocations int64 `json:"invocations"`
+	Errors      int64 `json:"errors"`
+}
+
+type stats struct {
+	mu      sync.Mutex
+	methods map[string]MethodStats
+}
+
+// WithStats counts invocations and errors per method in memory, for inspection during development
+func (s *Service) WithStats() *Service {
+	s.EnableStats = true
+	return s
+}
+
+// Stats returns a copy of the invocation counts per method, which is empty unless stats are enabled
+func (s *Service) Stats() map[string]MethodStats {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	res := make(map[string]MethodStats, len(s.stats.methods))
+	for name, ms := range s.stats.methods {
+		res[name] = ms
+	}
+
+	return res
+}
+
+func (s *Service) recordStats(m *Method, err error) {
+	if !s.EnableStats {
+		return
+	}
+
+	methodName := m.Name
+	if m.isFallback {
+		methodName = FallbackStatsName
+	}
+
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	if s.stats.methods == nil {
+		s.stats.methods = map[string]MethodStats{}
+	}
+
+	ms := s.stats.methods[methodName]
+	ms.Invocations++
+	if err != nil {
+		ms.Errors++
+	}
+	s.stats.methods[methodName] = ms
+}
@@ -0,0 +1,51 @@
+package rpcservice
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+)
+
+func TestStats(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithStats().
+		AddMethod("noop", noop, nil).
+		AddMethod("fail", func(ctx context.Context) error {
+			return hand.New(runtime.ErrCodeBadRequest)
+		}, nil).
+		SetFallback(legacyProxy)
+
+	for _, name := range []string{"noop", "noop", "fail"} {
+		svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent(name, nil))
+	}
+	for i := 0; i < 50; i++ {
+		svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent(fmt.Sprintf("unregistered%d", i), nil))
+	}
+
+	stats := svc.Stats()
+
+	if stats["noop"] != (MethodStats{Invocations: 2}) {
+		t.Errorf("unexpected noop stats %+v", stats["noop"])
+	}
+	if stats["fail"] != (MethodStats{Invocations: 1, Errors: 1}) {
+		t.Errorf("unexpected fail stats %+v", stats["fail"])
+	}
+	if stats[FallbackStatsName].Invocations != 50 {
+		t.Errorf("expected all fallback requests under one entry, got %+v", stats[FallbackStatsName])
+	}
+	if len(stats) != 3 {
+		t.Errorf("expected unknown method names not to add entries, got %v", stats)
+	}
+}
+
+func TestStatsDisabled(t *testing.T) {
+	svc := NewService(testLogger()).AddMethod("noop", noop, nil)
+	svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("noop", nil))
+
+	if stats := svc.Stats(); len(stats) != 0 {
+		t.Fatalf("expected no stats unless enabled, got %v", stats)
+	}
+}
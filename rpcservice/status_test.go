@@ -0,0 +1,94 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		code   string
+		status int
+	}{
+		{runtime.ErrCodeConflict, http.StatusConflict},
+		{runtime.ErrCodeNotFound, http.StatusNotFound},
+		{runtime.ErrCodeForbidden, http.StatusForbidden},
+		{"something_broke", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			svc := NewService(testLogger()).AddMethod("fail", func(ctx context.Context) error {
+				return hand.New(tt.code).WithMessage("failed")
+			}, nil)
+
+			res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("fail", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tt.status || !strings.Contains(res.Body, tt.code) {
+				t.Fatalf("expected %d with code %s, got %d: %s", tt.status, tt.code, res.StatusCode, res.Body)
+			}
+		})
+	}
+}
+
+func TestUnknownMethodNotFound(t *testing.T) {
+	svc := NewService(testLogger()).AddMethod("noop", noop, nil)
+
+	res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("missing", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusNotFound || !strings.Contains(res.Body, runtime.ErrCodeMethodNotFound) {
+		t.Fatalf("expected 404 method_not_found, got %d: %s", res.StatusCode, res.Body)
+	}
+}
+
+type createdResponse struct {
+	ID string `json:"id"`
+}
+
+func createItem(ctx context.Context) (*createdResponse, error) {
+	return &createdResponse{ID: "item_1"}, nil
+}
+
+func createNothing(ctx context.Context) (*createdResponse, error) {
+	return nil, nil
+}
+
+func TestSuccessStatus(t *testing.T) {
+	svc := NewService(testLogger()).
+		AddMethod("create", createItem, nil).
+		AddMethod("createNothing", createNothing, nil).
+		AddMethod("get", createItem, nil)
+
+	for _, name := range []string{"create", "createNothing"} {
+		method, _ := svc.GetMethod(name)
+		method.WithSuccessStatus(http.StatusCreated)
+	}
+
+	tests := []struct {
+		method string
+		status int
+	}{
+		{"create", http.StatusCreated},
+		{"createNothing", http.StatusNoContent},
+		{"get", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent(tt.method, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != tt.status {
+			t.Errorf("%s: expected %d, got %d: %s", tt.method, tt.status, res.StatusCode, res.Body)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package rpcservice
+
+// StreamResponse can be returned by a handler to respond with a JSON array whose items are produced incrementally.
+// The handler must close Items once every item has been sent. The devserver writes items as they arrive,
+// while the Lambda wrappers collect them into a single buffered response.
+// func(ctx context.Context) (response *rpcservice.StreamResponse, err error)
+type StreamResponse struct {
+	Items <-chan interface{}
+}
+
+// Collect drains the stream into a slice
+func (s *StreamResponse) Collect() []interface{} {
+	items := []interface{}{}
+	for item := range s.Items {
+		items = append(items, item)
+	}
+
+	return items
+}
@@ -0,0 +1,52 @@
+package rpcservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/g-wilson/runtime"
+)
+
+// TenantExtractor finds the tenant of the caller in the access token claims of the current request
+type TenantExtractor func(claims map[string]interface{}) (string, bool)
+
+// TenantClaim returns a TenantExtractor which reads the tenant from the named claim
+func TenantClaim(name string) TenantExtractor {
+	return func(claims map[string]interface{}) (string, bool) {
+		switch val := claims[name].(type) {
+		case string:
+			return val, val != ""
+		case nil:
+			return "", false
+		default:
+			return fmt.Sprint(val), true
+		}
+	}
+}
+
+// WithTenantExtractor sets the function used to find the tenant of each authenticated request, which handlers read
+// with runtime.TenantFromContext. It runs alongside the identity provider, before the context providers.
+func (s *Service) WithTenantExtractor(fn TenantExtractor) *Service {
+	s.TenantExtractor = fn
+	return s
+}
+
+// UsesIdentity reports whether the service needs the access token claims of requests, to provide an identity or tenant
+func (s *Service) UsesIdentity() bool {
+	return s.IdentityProvider != nil || s.TenantExtractor != nil
+}
+
+// ProvideIdentity adds the tenant and identity found in the access token claims of the current request to the context
+func (s *Service) ProvideIdentity(ctx context.Context, claims map[string]interface{}) context.Context {
+	if s.TenantExtractor != nil {
+		if tenant, ok := s.TenantExtractor(claims); ok {
+			ctx = runtime.WithTenant(ctx, tenant)
+		}
+	}
+
+	if s.IdentityProvider != nil {
+		ctx = s.IdentityProvider(ctx, claims)
+	}
+
+	return ctx
+}
@@ -0,0 +1,98 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+)
+
+type tenantResponse struct {
+	Tenant string `json:"tenant"`
+}
+
+func currentTenant(ctx context.Context) (*tenantResponse, error) {
+	tenant, _ := runtime.TenantFromContext(ctx)
+	return &tenantResponse{Tenant: tenant}, nil
+}
+
+func TestTenantClaim(t *testing.T) {
+	extract := TenantClaim("tenant")
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		tenant string
+		ok     bool
+	}{
+		{"string", map[string]interface{}{"tenant": "acme"}, "acme", true},
+		{"number", map[string]interface{}{"tenant": float64(42)}, "42", true},
+		{"empty", map[string]interface{}{"tenant": ""}, "", false},
+		{"missing", map[string]interface{}{"sub": "user_1"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, ok := extract(tt.claims)
+			if tenant != tt.tenant || ok != tt.ok {
+				t.Fatalf("expected (%q, %v), got (%q, %v)", tt.tenant, tt.ok, tenant, ok)
+			}
+		})
+	}
+}
+
+func TestTenantExtractorHTTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]string
+		tenant string
+	}{
+		{"tenant claim", map[string]string{"sub": "user_1", "tenant": "acme"}, "acme"},
+		{"no tenant claim", map[string]string{"sub": "user_1"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(testLogger()).
+				WithTenantExtractor(TenantClaim("tenant")).
+				AddMethod("currentTenant", currentTenant, nil)
+
+			res, err := svc.WrapAPIGatewayHTTP()(context.Background(), httpEvent("currentTenant", tt.claims))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var body tenantResponse
+			if err := json.Unmarshal([]byte(res.Body), &body); err != nil {
+				t.Fatalf("%v: %s", err, res.Body)
+			}
+			if body.Tenant != tt.tenant {
+				t.Fatalf("expected tenant %q, got %q", tt.tenant, body.Tenant)
+			}
+		})
+	}
+}
+
+func TestProvideIdentityRunsTenantBeforeIdentity(t *testing.T) {
+	var seen string
+	svc := NewService(testLogger()).
+		WithTenantExtractor(TenantClaim("tenant")).
+		WithIdentityProvider(func(ctx context.Context, claims map[string]interface{}) context.Context {
+			seen, _ = runtime.TenantFromContext(ctx)
+			return ctx
+		})
+
+	if !svc.UsesIdentity() {
+		t.Fatal("expected a service with a tenant extractor to use identity")
+	}
+
+	ctx := svc.ProvideIdentity(context.Background(), map[string]interface{}{"tenant": "acme"})
+
+	if tenant, _ := runtime.TenantFromContext(ctx); tenant != "acme" {
+		t.Fatalf("expected tenant acme, got %q", tenant)
+	}
+	if seen != "acme" {
+		t.Fatalf("expected the identity provider to see the tenant, got %q", seen)
+	}
+}
@@ -0,0 +1,79 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/logger"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+const testTraceID = "1-5759e988-bd862e3fe1be46a994272793"
+
+func traceErrorResponse(t *testing.T, headers map[string]string) (hand.E, *test.Hook) {
+	log, hook := test.NewNullLogger()
+
+	svc := NewService(logrus.NewEntry(log)).AddMethod("fail", func(ctx context.Context) error {
+		return hand.New(runtime.ErrCodeNotFound)
+	}, nil)
+
+	event := httpEvent("fail", nil)
+	event.Headers = headers
+
+	res, err := svc.WrapAPIGatewayHTTP()(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handErr hand.E
+	if err := json.Unmarshal([]byte(res.Body), &handErr); err != nil {
+		t.Fatal(err)
+	}
+
+	return handErr, hook
+}
+
+func TestXRayTraceID(t *testing.T) {
+	handErr, hook := traceErrorResponse(t, map[string]string{"x-amzn-trace-id": "Root=" + testTraceID + ";Sampled=1"})
+
+	if handErr.Meta["trace_id"] != testTraceID {
+		t.Fatalf("expected the trace id in the error response, got %v", handErr.Meta)
+	}
+	if len(hook.AllEntries()) == 0 {
+		t.Fatal("expected the request to be logged")
+	}
+	for _, entry := range hook.AllEntries() {
+		if entry.Data[logger.TraceIDKey] != testTraceID {
+			t.Fatalf("expected the trace id on every log entry, got %v", entry.Data)
+		}
+	}
+}
+
+func TestXRayTraceIDFromEnvironment(t *testing.T) {
+	os.Setenv("_X_AMZN_TRACE_ID", "Root="+testTraceID)
+	defer os.Unsetenv("_X_AMZN_TRACE_ID")
+
+	handErr, _ := traceErrorResponse(t, nil)
+	if handErr.Meta["trace_id"] != testTraceID {
+		t.Fatalf("expected the trace id from the environment, got %v", handErr.Meta)
+	}
+}
+
+func TestWithoutXRayTraceID(t *testing.T) {
+	handErr, hook := traceErrorResponse(t, nil)
+
+	if _, ok := handErr.Meta["trace_id"]; ok {
+		t.Fatalf("expected no trace id without a trace header, got %v", handErr.Meta)
+	}
+	for _, entry := range hook.AllEntries() {
+		if _, ok := entry.Data[logger.TraceIDKey]; ok {
+			t.Fatalf("expected no trace id log field, got %v", entry.Data)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const preciseAmount = "12345678901234567890.123456789"
+
+type paymentRequest struct {
+	Amount interface{} `json:"amount"`
+}
+
+type paymentResponse struct {
+	Amount interface{} `json:"amount"`
+	Type   string      `json:"type"`
+}
+
+func pay(ctx context.Context, req *paymentRequest) (*paymentResponse, error) {
+	return &paymentResponse{Amount: req.Amount, Type: fmt.Sprintf("%T", req.Amount)}, nil
+}
+
+func paymentService(maximum string) *Service {
+	return NewService(testLogger()).
+		WithUseNumber().
+		AddMethod("pay", pay, gojsonschema.NewStringLoader(`{
+			"type": "object",
+			"properties": {"amount": {"type": "number", "maximum": `+maximum+`}}
+		}`))
+}
+
+func TestUseNumberRoundTrip(t *testing.T) {
+	event := httpEvent("pay", nil)
+	event.Body = `{"amount": ` + preciseAmount + `}`
+
+	res, err := paymentService(preciseAmount).WrapAPIGatewayHTTP()(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected success, got %d: %s", res.StatusCode, res.Body)
+	}
+
+	var decoded struct {
+		Amount json.RawMessage `json:"amount"`
+		Type   string          `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(res.Body), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Type != "json.Number" {
+		t.Fatalf("expected the handler to receive a json.Number, got %s", decoded.Type)
+	}
+	if string(decoded.Amount) != preciseAmount {
+		t.Fatalf("expected %s to round trip exactly, got %s", preciseAmount, decoded.Amount)
+	}
+}
+
+func TestUseNumberValidationKeepsPrecision(t *testing.T) {
+	// as float64 both amounts are 12345678901234567000, so the maximum is only enforced if precision is kept
+	event := httpEvent("pay", nil)
+	event.Body = `{"amount": 12345678901234567890.123456790}`
+
+	res, err := paymentService(preciseAmount).WrapAPIGatewayHTTP()(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusBadRequest || !strings.Contains(res.Body, `"keyword":"maximum"`) {
+		t.Fatalf("expected the amount above the maximum to fail validation, got %d: %s", res.StatusCode, res.Body)
+	}
+}
+
+func TestWithoutUseNumberDecodesFloat(t *testing.T) {
+	svc := NewService(testLogger()).AddMethod("pay", pay, gojsonschema.NewStringLoader(`{"type": "object"}`))
+	method, _ := svc.GetMethod("pay")
+
+	result, err := svc.Invoke(testContext(), method, []byte(`{"amount": `+preciseAmount+`}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ := result.(*paymentResponse).Type; typ != "float64" {
+		t.Fatalf("expected float64 by default, got %s", typ)
+	}
+}
@@ -0,0 +1,75 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+type webhookResponse struct {
+	Received string `json:"received"`
+}
+
+func receiveWebhook(ctx context.Context, body *json.RawMessage) (*webhookResponse, error) {
+	return &webhookResponse{Received: string(*body)}, nil
+}
+
+type looseRequest struct {
+	Name string `json:"name"`
+}
+
+func receiveLoose(ctx context.Context, req *looseRequest) (*webhookResponse, error) {
+	return &webhookResponse{Received: req.Name}, nil
+}
+
+func TestAddMethodWithoutValidation(t *testing.T) {
+	svc := NewService(testLogger()).
+		AddMethodWithoutValidation("webhook", receiveWebhook).
+		AddMethodWithoutValidation("loose", receiveLoose)
+
+	if err := svc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method   string
+		body     string
+		received string
+	}{
+		{"webhook", `{"event": "push", "commits": [1, 2, {"nested": true}]}`, `{"event": "push", "commits": [1, 2, {"nested": true}]}`},
+		{"webhook", `[1, "two", null]`, `[1, "two", null]`},
+		{"loose", `{"name": "alice", "unexpected": {"anything": [1]}}`, "alice"},
+	}
+
+	for _, tt := range tests {
+		event := httpEvent(tt.method, nil)
+		event.Body = tt.body
+
+		res, err := svc.WrapAPIGatewayHTTP()(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected success, got %d: %s", tt.method, res.StatusCode, res.Body)
+		}
+
+		var decoded webhookResponse
+		if err := json.Unmarshal([]byte(res.Body), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Received != tt.received {
+			t.Fatalf("%s: expected the handler to receive %s, got %s", tt.method, tt.received, decoded.Received)
+		}
+	}
+}
+
+func TestRequestTypeRequiresSchema(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a method with a request type and no schema to be rejected")
+		}
+	}()
+
+	NewService(testLogger()).AddMethod("loose", receiveLoose, nil)
+}
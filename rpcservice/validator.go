@@ -0,0 +1,140 @@
+package rpcservice
+
+import (
+	"fmt"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Validator compiles and validates JSON Schemas, so the schema library used by a service can be replaced
+type Validator interface {
+	Compile(schema gojsonschema.JSONLoader) (CompiledSchema, error)
+	// Validate returns a *SchemaError when the data does not conform, or any other error if it cannot be validated
+	Validate(compiled CompiledSchema, data []byte) error
+}
+
+// RefResolver is implemented by Validators which can resolve $refs to shared schemas registered on the service
+type RefResolver interface {
+	// CompileWithRefs compiles a schema whose $refs may point at the shared schemas, keyed by their URL
+	CompileWithRefs(schema gojsonschema.JSONLoader, shared map[string]gojsonschema.JSONLoader) (CompiledSchema, error)
+}
+
+// CompiledSchema is a schema prepared by a Validator
+type CompiledSchema interface{}
+
+// SchemaError is returned by a Validator when data does not conform to a schema
+type SchemaError struct {
+	Reasons []SchemaErrorReason
+}
+
+// SchemaErrorReason describes a single schema violation.
+// Keyword and Params are machine readable, so clients can localize the violation instead of displaying the English Message.
+type SchemaErrorReason struct {
+	Field   string
+	Type    string
+	Message string
+	Keyword string
+	Params  map[string]interface{}
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("schema validation failed with %d errors", len(e.Reasons))
+}
+
+// GoJSONSchemaValidator is the default Validator, backed by github.com/xeipuuv/gojsonschema
+type GoJSONSchemaValidator struct{}
+
+// Compile implements Validator
+func (GoJSONSchemaValidator) Compile(schema gojsonschema.JSONLoader) (CompiledSchema, error) {
+	return gojsonschema.NewSchema(schema)
+}
+
+// CompileWithRefs implements RefResolver
+func (GoJSONSchemaValidator) CompileWithRefs(schema gojsonschema.JSONLoader, shared map[string]gojsonschema.JSONLoader) (CompiledSchema, error) {
+	sl := gojsonschema.NewSchemaLoader()
+
+	for url, loader := range shared {
+		if err := sl.AddSchema(url, loader); err != nil {
+			return nil, fmt.Errorf("shared schema %s: %w", url, err)
+		}
+	}
+
+	return sl.Compile(schema)
+}
+
+// Validate implements Validator
+func (GoJSONSchemaValidator) Validate(compiled CompiledSchema, data []byte) error {
+	sc, ok := compiled.(*gojsonschema.Schema)
+	if !ok {
+		return fmt.Errorf("gojsonschema validator cannot use schema of type %T", compiled)
+	}
+
+	result, err := sc.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return err
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	schemaErr := &SchemaError{}
+	for _, re := range result.Errors() {
+		schemaErr.Reasons = append(schemaErr.Reasons, SchemaErrorReason{
+			Field:   re.Field(),
+			Type:    re.Type(),
+			Message: re.Description(),
+			Keyword: gojsonschemaKeywords[re.Type()],
+			Params:  gojsonschemaParams(re.Details()),
+		})
+	}
+
+	return schemaErr
+}
+
+// gojsonschemaKeywords maps gojsonschema error types to the JSON Schema keyword which was violated
+var gojsonschemaKeywords = map[string]string{
+	"false":                           "false",
+	"required":                        "required",
+	"invalid_type":                    "type",
+	"number_any_of":                   "anyOf",
+	"number_one_of":                   "oneOf",
+	"number_all_of":                   "allOf",
+	"number_not":                      "not",
+	"missing_dependency":              "dependencies",
+	"const":                           "const",
+	"enum":                            "enum",
+	"array_no_additional_items":       "additionalItems",
+	"array_min_items":                 "minItems",
+	"array_max_items":                 "maxItems",
+	"unique":                          "uniqueItems",
+	"contains":                        "contains",
+	"array_min_properties":            "minProperties",
+	"array_max_properties":            "maxProperties",
+	"additional_property_not_allowed": "additionalProperties",
+	"invalid_property_pattern":        "patternProperties",
+	"invalid_property_name":           "propertyNames",
+	"string_gte":                      "minLength",
+	"string_lte":                      "maxLength",
+	"pattern":                         "pattern",
+	"format":                          "format",
+	"multiple_of":                     "multipleOf",
+	"number_gte":                      "minimum",
+	"number_gt":                       "exclusiveMinimum",
+	"number_lte":                      "maximum",
+	"number_lt":                       "exclusiveMaximum",
+	"condition_then":                  "then",
+	"condition_else":                  "else",
+}
+
+// gojsonschemaParams returns the parameters of a violation, without the location details gojsonschema adds to every error
+func gojsonschemaParams(details gojsonschema.ErrorDetails) map[string]interface{} {
+	params := map[string]interface{}{}
+	for key, val := range details {
+		if key == "context" || key == "field" {
+			continue
+		}
+		params[key] = val
+	}
+
+	return params
+}
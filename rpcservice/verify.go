@@ -0,0 +1,55 @@
+package rpcservice
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+)
+
+// RequestVerifier checks a request using its raw body, as received before decompression, decoding or validation
+type RequestVerifier func(ctx context.Context, rawBody []byte) error
+
+// WithRequestVerifier adds a verifier which every request must pass before its body is processed
+func (s *Service) WithRequestVerifier(v RequestVerifier) *Service {
+	s.RequestVerifiers = append(s.RequestVerifiers, v)
+	return s
+}
+
+// VerifyRequest runs the request verifiers against the raw request body. Execution environments call it before
+// transforming the body in any way.
+func (s *Service) VerifyRequest(ctx context.Context, rawBody []byte) error {
+	for _, v := range s.RequestVerifiers {
+		if err := v(ctx, rawBody); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WithWebhookSignature rejects requests unless the header carries the hex encoded HMAC-SHA256 of the raw body signed
+// with secret, optionally prefixed with "sha256=" as sent by GitHub
+func (s *Service) WithWebhookSignature(header string, secret []byte) *Service {
+	s.RequestMetaHeaders = append(s.RequestMetaHeaders, header)
+
+	return s.WithRequestVerifier(func(ctx context.Context, rawBody []byte) error {
+		sig, err := hex.DecodeString(strings.TrimPrefix(runtime.RequestHeader(ctx, header), "sha256="))
+		if err != nil || len(sig) == 0 {
+			return hand.New(runtime.ErrCodeForbidden).WithMessage("request signature missing or malformed")
+		}
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(rawBody)
+
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return hand.New(runtime.ErrCodeForbidden).WithMessage("request signature invalid")
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,59 @@
+package rpcservice
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"testing"
+)
+
+var webhookSecret = []byte("whsec_test")
+
+func signBody(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookSignature(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithWebhookSignature("X-Hub-Signature-256", webhookSecret).
+		AddMethod("echo", echo, echoSchema)
+
+	compressed := gzipBytes(t, echoJSON)
+
+	tests := []struct {
+		name      string
+		body      []byte
+		encoding  string
+		signature string
+		status    int
+	}{
+		{"valid", echoJSON, "", signBody(webhookSecret, echoJSON), http.StatusOK},
+		{"valid with prefix", echoJSON, "", "sha256=" + signBody(webhookSecret, echoJSON), http.StatusOK},
+		{"signed raw gzip body", compressed, "gzip", signBody(webhookSecret, compressed), http.StatusOK},
+		{"signed with another secret", echoJSON, "", signBody([]byte("other"), echoJSON), http.StatusForbidden},
+		{"missing", echoJSON, "", "", http.StatusForbidden},
+		{"malformed", echoJSON, "", "not-hex", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := httpEvent("echo", nil)
+			event.Body = base64.StdEncoding.EncodeToString(tt.body)
+			event.IsBase64Encoded = true
+			event.Headers = map[string]string{"x-hub-signature-256": tt.signature, "content-encoding": tt.encoding}
+
+			res, err := svc.WrapAPIGatewayHTTP()(context.Background(), event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, res.StatusCode, res.Body)
+			}
+		})
+	}
+}
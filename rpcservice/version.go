@@ -0,0 +1,165 @@
+package rpcservice
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/internal/ctxkey"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+var acceptVersionKey = ctxkey.New("acceptversion")
+
+// DefaultMethodVersion is the version of a method added with AddMethod once other versions of it are added
+const DefaultMethodVersion = "1"
+
+// AddMethodVersion adds a version of a method with its own handler and schema. Clients select a version with the
+// Accept-Version header, and requests without one are served by the highest version. A method added with AddMethod
+// remains available as DefaultMethodVersion. Versions share the options of the method, such as WithAllowAnonymous and
+// WithRateLimit, whether those are set before or after the version is added.
+func (s *Service) AddMethodVersion(methodName, version string, handler interface{}, schema gojsonschema.JSONLoader) *Service {
+	method := &Method{
+		Name:    methodName,
+		Version: version,
+		Handler: handler,
+		service: s,
+	}
+
+	if schema != nil {
+		sc, ok := s.registerSchema(fmt.Sprintf("%s version %s", methodName, version), schema)
+		if !ok {
+			return s
+		}
+
+		method.CompiledSchema = sc
+	}
+
+	hasReqBody, hasResBody, err := validateMethod(method)
+	if err != nil {
+		panic(fmt.Errorf("runtime cannot add rpc method %s version %s: %w", methodName, version, err))
+	}
+	method.expectsRequestBody = hasReqBody
+	method.expectsResponseBody = hasResBody
+
+	base, ok := s.Methods[s.normalizeMethodName(methodName)]
+	if !ok {
+		s.addMethod(method)
+		base = method
+	}
+
+	if len(base.Versions) == 0 {
+		if base.Version == "" {
+			base.Version = DefaultMethodVersion
+		}
+		base.Versions = []*Method{base}
+	}
+
+	for _, v := range base.Versions {
+		if v.Version == version && v != method {
+			panic(fmt.Errorf("runtime cannot add rpc method %s: version %s already exists", methodName, version))
+		}
+	}
+
+	if method != base {
+		method.inheritOptions(base)
+		base.Versions = append(base.Versions, method)
+	}
+
+	return s
+}
+
+// apply sets an option on the method and every version of it, as versions share the options of the method
+func (m *Method) apply(set func(m *Method)) *Method {
+	set(m)
+	for _, v := range m.Versions {
+		if v != m {
+			set(v)
+		}
+	}
+
+	return m
+}
+
+// inheritOptions copies the options which versions share from the base method
+func (m *Method) inheritOptions(base *Method) {
+	m.Deprecation = base.Deprecation
+	m.AllowAnonymous = base.AllowAnonymous
+	m.Idempotent = base.Idempotent
+	m.HTTPVerb = base.HTTPVerb
+	m.SuccessStatus = base.SuccessStatus
+	m.LogSampleRate = base.LogSampleRate
+	m.Limiter = base.Limiter
+	m.Audited = base.Audited
+}
+
+// ResolveVersion returns the version of the method requested by an Accept-Version header value, or the highest
+// version when none is requested. Methods without versions resolve to themselves.
+func (m *Method) ResolveVersion(requested string) (*Method, string, error) {
+	if len(m.Versions) == 0 {
+		return m, "", nil
+	}
+
+	if requested == "" {
+		latest := m.Versions[0]
+		for _, v := range m.Versions[1:] {
+			if compareVersions(v.Version, latest.Version) > 0 {
+				latest = v
+			}
+		}
+		return latest, latest.Version, nil
+	}
+
+	for _, v := range m.Versions {
+		if v.Version == requested {
+			return v, v.Version, nil
+		}
+	}
+
+	return nil, "", hand.New(runtime.ErrCodeBadRequest).WithMessagef("unknown version %s of method %s", requested, m.Name)
+}
+
+// WithAcceptVersion adds the Accept-Version header of a request to the context, so that HandleJSONRPC serves the
+// requested version of each method
+func WithAcceptVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, acceptVersionKey, version)
+}
+
+func acceptVersionFromContext(ctx context.Context) string {
+	version, _ := ctx.Value(acceptVersionKey).(string)
+	return version
+}
+
+// compareVersions orders versions such as "2", "v2" and "2.1" by their numeric parts, so "10" is later than "9".
+// Parts which are not numbers are compared as strings.
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(strings.ToLower(a), "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(strings.ToLower(b), "v"), ".")
+
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		if i >= len(pa) {
+			return -1
+		}
+		if i >= len(pb) {
+			return 1
+		}
+
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && pa[i] != pb[i]:
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+
+	return 0
+}
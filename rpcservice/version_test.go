@@ -0,0 +1,112 @@
+package rpcservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type versionResponse struct {
+	Version string `json:"version"`
+}
+
+func versionHandler(version string) func(ctx context.Context) (*versionResponse, error) {
+	return func(ctx context.Context) (*versionResponse, error) {
+		return &versionResponse{Version: version}, nil
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	svc := NewService(testLogger()).
+		AddMethod("get", versionHandler("1"), nil).
+		AddMethodVersion("get", "2", versionHandler("2"), nil).
+		AddMethodVersion("get", "10", versionHandler("10"), nil).
+		AddMethodVersion("get", "9", versionHandler("9"), nil)
+
+	base, _ := svc.GetMethod("get")
+
+	tests := []struct {
+		requested string
+		resolved  string
+	}{
+		{"", "10"},
+		{"1", "1"},
+		{"2", "2"},
+		{"9", "9"},
+	}
+
+	for _, tt := range tests {
+		m, version, err := base.ResolveVersion(tt.requested)
+		if err != nil {
+			t.Fatalf("resolving %q: %v", tt.requested, err)
+		}
+		if version != tt.resolved || m.Version != tt.resolved {
+			t.Fatalf("expected %q to resolve to version %s, got %s", tt.requested, tt.resolved, version)
+		}
+	}
+
+	if _, _, err := base.ResolveVersion("3"); err == nil {
+		t.Fatal("expected unknown version to fail")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2", "10", -1},
+		{"v10", "v9", 1},
+		{"2.1", "2", 1},
+		{"2.0", "2.0", 0},
+		{"beta", "alpha", 1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+type denyLimiter struct{}
+
+func (denyLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	return false, time.Second, nil
+}
+
+func TestVersionsShareMethodOptions(t *testing.T) {
+	svc := NewService(testLogger()).
+		WithRequireAuthentication().
+		AddMethod("get", versionHandler("1"), nil)
+
+	base, _ := svc.GetMethod("get")
+	base.WithAllowAnonymous()
+
+	svc.AddMethodVersion("get", "2", versionHandler("2"), nil)
+
+	// options set after a version is added apply to it too
+	base.WithRateLimit(denyLimiter{})
+
+	handler := svc.WrapAPIGatewayHTTP()
+
+	for _, version := range []string{"1", "2"} {
+		t.Run("version "+version, func(t *testing.T) {
+			res, err := handler(context.Background(), events.APIGatewayV2HTTPRequest{
+				PathParameters: map[string]string{"method": "get"},
+				Headers:        map[string]string{"accept-version": version},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// anonymous access passes authentication, so the request reaches the rate limit
+			if res.StatusCode != http.StatusTooManyRequests {
+				t.Fatalf("expected rate limited response, got %d: %s", res.StatusCode, res.Body)
+			}
+		})
+	}
+}
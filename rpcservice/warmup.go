@@ -0,0 +1,29 @@
+package rpcservice
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// WarmupDetector reports whether an API Gateway request is a scheduled warming ping rather than a real request
+type WarmupDetector func(event events.APIGatewayV2HTTPRequest) bool
+
+// WithWarmup answers requests matched by detect with 200 OK without dispatching them to a method, keeping warming
+// pings out of request logs and metrics. If warm is not nil it is called on each ping, to initialise lazy resources.
+func (s *Service) WithWarmup(detect WarmupDetector, warm func(ctx context.Context) error) *Service {
+	s.WarmupDetector = detect
+	s.Warmer = warm
+	return s
+}
+
+// IsWarmerRequest is a WarmupDetector matching invocations which did not come through API Gateway, such as a
+// scheduled ping with a {"warmer": true} payload. Such payloads decode to an event without a request context.
+// Requests routed by API Gateway are never matched, whatever their headers or body, so callers cannot use a warming
+// ping to skip authentication.
+func IsWarmerRequest(event events.APIGatewayV2HTTPRequest) bool {
+	return event.RequestContext.RequestID == "" &&
+		event.RequestContext.HTTP.Method == "" &&
+		event.RouteKey == "" &&
+		event.RawPath == ""
+}
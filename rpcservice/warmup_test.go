@@ -0,0 +1,62 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestWarmupDetection(t *testing.T) {
+	warmed := 0
+	svc := NewService(testLogger()).
+		WithRequireAuthentication().
+		WithWarmup(IsWarmerRequest, func(ctx context.Context) error {
+			warmed++
+			return nil
+		}).
+		AddMethod("noop", noop, nil)
+
+	handler := svc.WrapAPIGatewayHTTP()
+
+	t.Run("scheduled ping payload", func(t *testing.T) {
+		// Lambda decodes the raw payload of a scheduled invocation into the event type
+		var event events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal([]byte(`{"warmer":true}`), &event); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := handler(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK || warmed != 1 {
+			t.Fatalf("expected ping to be answered by the warmer, got %d after %d warms", res.StatusCode, warmed)
+		}
+	})
+
+	t.Run("routed request with warmer body", func(t *testing.T) {
+		event := events.APIGatewayV2HTTPRequest{
+			RouteKey:       "POST /{method}",
+			RawPath:        "/noop",
+			Body:           `{"warmer": true}`,
+			Headers:        map[string]string{"x-warmer": "true"},
+			PathParameters: map[string]string{"method": "noop"},
+		}
+		event.RequestContext.RequestID = "req_1"
+		event.RequestContext.HTTP.Method = http.MethodPost
+
+		res, err := handler(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected routed request to require authentication, got %d: %s", res.StatusCode, res.Body)
+		}
+		if warmed != 1 {
+			t.Fatal("expected routed request not to run the warmer")
+		}
+	})
+}
@@ -2,10 +2,12 @@ package rpcservice
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/g-wilson/runtime"
@@ -13,6 +15,7 @@ import (
 	"github.com/g-wilson/runtime/logger"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
 )
 
 // LambdaAPIGatewayHandler is the expected function signature for AWS Lambda functions consuming events from API Gateway
@@ -21,111 +24,288 @@ type LambdaAPIGatewayHandler func(context.Context, events.APIGatewayV2HTTPReques
 // WrapAPIGatewayHTTP wraps the service methods and returns a Lambda compatible handler function for HTTP API Gateway requests
 func (s *Service) WrapAPIGatewayHTTP() LambdaAPIGatewayHandler {
 	return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (res events.APIGatewayProxyResponse, err error) {
-		ctx = logger.SetContext(ctx, s.Logger.WithField("apig_request_id", event.RequestContext.RequestID))
+		ctx = s.apiGatewayContext(ctx, event)
 		reqLogger := logger.FromContext(ctx)
 
-		if s.IdentityProvider != nil {
-			authdata := event.RequestContext.Authorizer.JWT
-			atclaims := map[string]interface{}{}
-			atclaims["scope"] = strings.Join(authdata.Scopes, " ")
-
-			for key, val := range authdata.Claims {
-				// apig jwt authorizer coerces audience to a string, split it for better compatibility
-				if key == "aud" {
-					atclaims["aud"] = strings.Split(strings.Trim(val, "[]"), " ")
-				} else {
-					atclaims[key] = val
+		if s.WarmupDetector != nil && s.WarmupDetector(event) {
+			if s.Warmer != nil {
+				if err := s.Warmer(ctx); err != nil {
+					reqLogger.Entry().WithError(err).Warn("warmup failed")
 				}
 			}
-
-			ctx = s.IdentityProvider(ctx, atclaims)
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
 		}
 
 		if len(event.PathParameters) < 1 {
 			reqLogger.Entry().WithError(errors.New("wrap http api gateway: no path parameters found")).Error("request failed")
-			return apiGatewayErrorResponse(hand.New("method_not_found")), nil
+			return s.apiGatewayErrorResponse(ctx, hand.New(runtime.ErrCodeMethodNotFound)), nil
 		}
 		methodName, ok := event.PathParameters["method"]
 		if !ok {
 			reqLogger.Entry().WithError(errors.New("wrap http api gateway: method path parameter not found")).Error("request failed")
-			return apiGatewayErrorResponse(hand.New("method_not_found")), nil
+			return s.apiGatewayErrorResponse(ctx, hand.New(runtime.ErrCodeMethodNotFound)), nil
 		}
 
 		handler, ok := s.GetMethod(methodName)
 		if !ok {
-			reqLogger.Entry().WithError(fmt.Errorf("wrap http api gateway: method with name %s not found", methodName)).Error("request failed")
-			return apiGatewayErrorResponse(hand.New("method_not_found")), nil
+			reqLogger.Entry().WithError(fmt.Errorf("wrap http api gateway: method with name %s not found", methodName)).Log(s.ErrorLogLevel(runtime.ErrCodeMethodNotFound), "request failed")
+			return s.apiGatewayErrorResponse(ctx, hand.New(runtime.ErrCodeMethodNotFound)), nil
+		}
+
+		reqLogger.Update(reqLogger.Entry().WithField("method", handler.Name))
+
+		handler, version, err := handler.ResolveVersion(headerValue(event.Headers, "Accept-Version"))
+		if err != nil {
+			return s.apiGatewayErrorResponse(ctx, err), nil
+		}
+		if version != "" {
+			reqLogger.Update(reqLogger.Entry().WithField("method_version", version))
+		}
+
+		if s.RequiresAuthentication(handler) && len(event.RequestContext.Authorizer.JWT.Claims) == 0 {
+			reqLogger.Entry().WithError(errors.New("wrap http api gateway: request has no authorizer claims")).Warn("request failed")
+			return s.apiGatewayErrorResponse(ctx, hand.New(runtime.ErrCodeNoAuthentication)), nil
 		}
 
-		for _, fn := range s.ContextProviders {
-			ctx = fn(ctx)
+		if err := s.checkTimeRemaining(ctx); err != nil {
+			reqLogger.Entry().WithError(err).Warn("request rejected near deadline")
+			return s.apiGatewayErrorResponse(ctx, err), nil
 		}
 
-		result, err := handler.Invoke(ctx, []byte(event.Body))
+		ctx, err = s.ProvideContext(ctx)
 		if err != nil {
-			return apiGatewayErrorResponse(err), nil
+			reqLogger.Entry().WithError(err).WithFields(logMetaFields(err)).Log(s.ErrorLogLevel(errorCode(err)), "request failed")
+			return s.apiGatewayErrorResponse(ctx, err), nil
+		}
+
+		ctx = runtime.WithResponseHeaders(ctx)
+		if version != "" {
+			runtime.SetResponseHeader(ctx, "Content-Version", version)
+		}
+
+		body, err := s.apiGatewayBody(event)
+		if err == nil {
+			err = s.VerifyRequest(ctx, body)
+		}
+		if err == nil {
+			body, err = s.DecompressRequestBody(headerValue(event.Headers, "Content-Encoding"), body)
+		}
+		if err == nil {
+			body, err = s.DecodeRequestBody(headerValue(event.Headers, "Content-Type"), body)
+		}
+		if err != nil {
+			return s.apiGatewayErrorResponse(ctx, err), nil
+		}
+
+		result, err := s.Invoke(ctx, handler, body)
+		if err != nil {
+			return withResponseHeaders(ctx, s.apiGatewayErrorResponse(ctx, err)), nil
+		}
+
+		if runtime.NotModified(ctx, headerValue(event.Headers, "If-None-Match")) {
+			return withResponseHeaders(ctx, events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotModified,
+			}), nil
+		}
+
+		// a fallback can return a nil binary response inside its interface{} result
+		if bin, ok := result.(*BinaryResponse); ok && bin == nil {
+			result = nil
 		}
 
 		if result == nil {
-			return events.APIGatewayProxyResponse{
+			return withResponseHeaders(ctx, events.APIGatewayProxyResponse{
 				StatusCode:      http.StatusNoContent,
 				Body:            "",
 				IsBase64Encoded: false,
-			}, nil
+			}), nil
+		}
+
+		if bin, ok := result.(*BinaryResponse); ok {
+			return s.limitResponseSize(ctx, handler, events.APIGatewayProxyResponse{
+				StatusCode:      handler.SuccessStatusCode(),
+				Body:            base64.StdEncoding.EncodeToString(bin.Body),
+				IsBase64Encoded: true,
+				Headers: map[string]string{
+					"Content-Type": bin.ContentType,
+				},
+			}), nil
+		}
+
+		if redirect, ok := result.(*runtime.Redirect); ok {
+			return withResponseHeaders(ctx, events.APIGatewayProxyResponse{
+				StatusCode: redirect.RedirectStatus(),
+				Headers: map[string]string{
+					"Location": redirect.URL,
+				},
+			}), nil
+		}
+
+		if stream, ok := result.(*StreamResponse); ok {
+			result = stream.Collect()
 		}
 
-		resBytes, err := json.Marshal(result)
+		resBytes, contentType, err := s.EncodeResponseBody(headerValue(event.Headers, "Accept"), s.EnvelopeResult(ctx, result))
 		if err != nil {
 			reqLogger.Entry().WithError(fmt.Errorf("wrap http api gateway: encoding response body failed: %w", err)).Error("request failed")
-			return apiGatewayErrorResponse(err), nil
+			return s.apiGatewayErrorResponse(ctx, err), nil
 		}
 
-		return events.APIGatewayProxyResponse{
-			StatusCode:      http.StatusOK,
+		if contentType == ContentTypeMsgpack {
+			return s.limitResponseSize(ctx, handler, events.APIGatewayProxyResponse{
+				StatusCode:      handler.SuccessStatusCode(),
+				Body:            base64.StdEncoding.EncodeToString(resBytes),
+				IsBase64Encoded: true,
+				Headers: map[string]string{
+					"Content-Type": contentType,
+				},
+			}), nil
+		}
+
+		return s.limitResponseSize(ctx, handler, events.APIGatewayProxyResponse{
+			StatusCode:      handler.SuccessStatusCode(),
 			Body:            string(resBytes),
 			IsBase64Encoded: false,
 			Headers: map[string]string{
-				"Content-Type": "application/json; charset=utf-8",
+				"Content-Type": contentType,
 			},
-		}, nil
+		}), nil
+	}
+}
+
+// limitResponseSize replaces a response whose body exceeds the service's limit with an error, then merges response headers
+func (s *Service) limitResponseSize(ctx context.Context, m *Method, res events.APIGatewayProxyResponse) events.APIGatewayProxyResponse {
+	if err := s.CheckResponseSize(ctx, m, len(res.Body)); err != nil {
+		res = s.apiGatewayErrorResponse(ctx, err)
 	}
+
+	return withResponseHeaders(ctx, res)
 }
 
-func apiGatewayErrorResponse(err error) events.APIGatewayProxyResponse {
-	var res []byte
-	var status int
+// withResponseHeaders merges headers set by the handler into the response
+func withResponseHeaders(ctx context.Context, res events.APIGatewayProxyResponse) events.APIGatewayProxyResponse {
+	headers := runtime.ResponseHeaders(ctx)
+	if len(headers) == 0 {
+		return res
+	}
 
-	if handErr, ok := err.(hand.E); ok {
-		switch handErr.Code {
-		case runtime.ErrCodeBadRequest:
-			fallthrough
-		case runtime.ErrCodeInvalidBody:
-			fallthrough
-		case runtime.ErrCodeSchemaFailure:
-			fallthrough
-		case runtime.ErrCodeMissingBody:
-			status = http.StatusBadRequest
+	if res.Headers == nil {
+		res.Headers = map[string]string{}
+	}
+	for key, values := range headers {
+		res.Headers[key] = strings.Join(values, ",")
+	}
+
+	return res
+}
+
+// apiGatewayContext prepares the request context with the logger, request details and identity from an API Gateway event
+func (s *Service) apiGatewayContext(ctx context.Context, event events.APIGatewayV2HTTPRequest) context.Context {
+	ctx = runtime.WithRequestID(ctx, event.RequestContext.RequestID)
+	reqEntry := s.Logger.WithField("apig_request_id", event.RequestContext.RequestID)
+
+	traceHeader := headerValue(event.Headers, "X-Amzn-Trace-Id")
+	if traceHeader == "" {
+		traceHeader = os.Getenv("_X_AMZN_TRACE_ID")
+	}
+	if traceID := runtime.ParseXRayTraceID(traceHeader); traceID != "" {
+		ctx = runtime.WithTraceID(ctx, traceID)
+		reqEntry = reqEntry.WithField(logger.TraceIDKey, traceID)
+	}
+
+	ctx = logger.SetContext(ctx, reqEntry)
+
+	ctx = runtime.WithRequestMeta(ctx, runtime.RequestMeta{
+		SourceIP:  event.RequestContext.HTTP.SourceIP,
+		UserAgent: event.RequestContext.HTTP.UserAgent,
+		Headers:   allowlistHeaders(event.Headers, s.RequestMetaHeaders),
+	})
+
+	if s.UsesIdentity() {
+		authdata := event.RequestContext.Authorizer.JWT
+		atclaims := map[string]interface{}{}
+		atclaims["scope"] = strings.Join(authdata.Scopes, " ")
+
+		for key, val := range authdata.Claims {
+			// apig jwt authorizer coerces audience to a string, split it for better compatibility
+			if key == "aud" {
+				atclaims["aud"] = strings.Split(strings.Trim(val, "[]"), " ")
+			} else {
+				atclaims[key] = val
+			}
+		}
+
+		ctx = s.ProvideIdentity(ctx, atclaims)
+	}
 
-		case runtime.ErrCodeForbidden:
-			status = http.StatusForbidden
+	return ctx
+}
+
+// WrapAPIGatewayJSONRPC returns a Lambda compatible handler function which serves the service as JSON-RPC 2.0 over HTTP API Gateway
+func (s *Service) WrapAPIGatewayJSONRPC() LambdaAPIGatewayHandler {
+	return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayProxyResponse, error) {
+		ctx = s.apiGatewayContext(ctx, event)
+		reqLogger := logger.FromContext(ctx)
+
+		if s.WarmupDetector != nil && s.WarmupDetector(event) {
+			if s.Warmer != nil {
+				if err := s.Warmer(ctx); err != nil {
+					reqLogger.Entry().WithError(err).Warn("warmup failed")
+				}
+			}
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+		}
 
-		case runtime.ErrCodeNoAuthentication:
-			fallthrough
-		case runtime.ErrCodeInvalidAuthentication:
-			status = http.StatusUnauthorized
+		ctx = WithAcceptVersion(ctx, headerValue(event.Headers, "Accept-Version"))
+		authenticated := len(event.RequestContext.Authorizer.JWT.Claims) > 0
 
-		default:
-			status = http.StatusInternalServerError
+		body, err := s.apiGatewayBody(event)
+		if err == nil {
+			err = s.checkTimeRemaining(ctx)
+			if err != nil {
+				reqLogger.Entry().WithError(err).Warn("request rejected near deadline")
+			}
 		}
 
-		res, _ = json.Marshal(handErr)
-	} else {
-		status = http.StatusInternalServerError
-		res, _ = json.Marshal(hand.New(runtime.ErrCodeUnknown))
+		var resBytes []byte
+		if err != nil {
+			resBytes = s.JSONRPCErrorResponse(body, err)
+		} else {
+			resBytes = s.HandleJSONRPC(ctx, body, authenticated)
+		}
+		if resBytes == nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}, nil
+		}
+
+		return events.APIGatewayProxyResponse{
+			StatusCode:      http.StatusOK,
+			Body:            string(resBytes),
+			IsBase64Encoded: false,
+			Headers: map[string]string{
+				"Content-Type": "application/json; charset=utf-8",
+			},
+		}, nil
+	}
+}
+
+func (s *Service) apiGatewayErrorResponse(ctx context.Context, err error) events.APIGatewayProxyResponse {
+	handErr, ok := s.RedactError(err).(hand.E)
+	if !ok {
+		handErr = hand.New(runtime.ErrCodeUnknown)
+	}
+
+	if traceID, ok := runtime.TraceIDFromContext(ctx); ok {
+		meta := hand.M{"trace_id": traceID}
+		for k, v := range handErr.Meta {
+			meta[k] = v
+		}
+		handErr = handErr.WithMeta(meta)
 	}
 
+	res, _ := json.Marshal(s.EnvelopeError(handErr))
+
 	return events.APIGatewayProxyResponse{
-		StatusCode:      status,
+		StatusCode:      runtime.HTTPStatus(handErr.Code),
 		Body:            string(res),
 		IsBase64Encoded: false,
 		Headers: map[string]string{
@@ -133,3 +313,69 @@ func apiGatewayErrorResponse(err error) events.APIGatewayProxyResponse {
 		},
 	}
 }
+
+// allowlistHeaders picks the named headers from a case-insensitive header map
+func allowlistHeaders(headers map[string]string, names []string) map[string]string {
+	res := map[string]string{}
+	if len(names) == 0 {
+		return res
+	}
+
+	canonical := http.Header{}
+	for key, val := range headers {
+		canonical.Set(key, val)
+	}
+
+	for _, name := range names {
+		if val := canonical.Get(name); val != "" {
+			res[http.CanonicalHeaderKey(name)] = val
+		}
+	}
+
+	return res
+}
+
+// errorCode returns the code of a hand error, or unknown for any other error
+func errorCode(err error) string {
+	if handErr, ok := err.(hand.E); ok {
+		return handErr.Code
+	}
+	return runtime.ErrCodeUnknown
+}
+
+// logMetaFields returns the logging-only metadata and cause chain of a hand error as log fields
+func logMetaFields(err error) logrus.Fields {
+	fields := logrus.Fields{}
+
+	if handErr, ok := err.(hand.E); ok {
+		for k, v := range handErr.LogMeta {
+			fields[k] = v
+		}
+		if handErr.Err != nil {
+			fields["err_cause"] = handErr.CauseDetail()
+		}
+	}
+
+	return fields
+}
+
+// headerValue reads a header from a case-insensitive header map
+func headerValue(headers map[string]string, name string) string {
+	return allowlistHeaders(headers, []string{name})[http.CanonicalHeaderKey(name)]
+}
+
+// apiGatewayBody returns the request body, decoding it if API Gateway delivered it base64 encoded,
+// and rejects bodies larger than the service's limit once decoded
+func (s *Service) apiGatewayBody(event events.APIGatewayV2HTTPRequest) ([]byte, error) {
+	body := []byte(event.Body)
+
+	if event.IsBase64Encoded {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(event.Body)
+		if err != nil {
+			return nil, hand.New(runtime.ErrCodeInvalidBody).WithMessage("base64 decoding error")
+		}
+	}
+
+	return body, checkRequestSize(body, s.MaxRequestBytes)
+}
@@ -0,0 +1,120 @@
+package rpcservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/g-wilson/runtime"
+	"github.com/g-wilson/runtime/hand"
+	"github.com/g-wilson/runtime/internal/ctxkey"
+	"github.com/g-wilson/runtime/logger"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
+)
+
+var connectionIDKey = ctxkey.New("connectionid")
+
+// ConnectionIDFromContext retrieves the API Gateway websocket connection ID from the context
+func ConnectionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(connectionIDKey).(string)
+	return id, ok
+}
+
+// LambdaAPIGatewayWebsocketHandler is the expected function signature for AWS Lambda functions consuming events from API Gateway websocket APIs
+type LambdaAPIGatewayWebsocketHandler func(context.Context, events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// WrapAPIGatewayWebsocket wraps the service methods and returns a Lambda compatible handler function for API Gateway websocket events.
+// The route key ($connect, $disconnect, $default or a custom route) is used as the method name.
+// Errors reject the connection on $connect, on other routes they are logged only.
+func (s *Service) WrapAPIGatewayWebsocket() LambdaAPIGatewayWebsocketHandler {
+	return func(ctx context.Context, event events.APIGatewayWebsocketProxyRequest) (res events.APIGatewayProxyResponse, err error) {
+		ctx = logger.SetContext(ctx, s.Logger.WithFields(logrus.Fields{
+			"apig_request_id":    event.RequestContext.RequestID,
+			"apig_connection_id": event.RequestContext.ConnectionID,
+		}))
+		ctx = context.WithValue(ctx, connectionIDKey, event.RequestContext.ConnectionID)
+		ctx = runtime.WithRequestID(ctx, event.RequestContext.RequestID)
+		reqLogger := logger.FromContext(ctx)
+
+		routeKey := event.RequestContext.RouteKey
+		isConnect := routeKey == "$connect"
+
+		handler, ok := s.GetMethod(routeKey)
+		if !ok {
+			reqLogger.Entry().WithError(fmt.Errorf("wrap websocket api gateway: method with name %s not found", routeKey)).Log(s.ErrorLogLevel(runtime.ErrCodeMethodNotFound), "request failed")
+			if isConnect {
+				return s.apiGatewayErrorResponse(ctx, hand.New(runtime.ErrCodeMethodNotFound)), nil
+			}
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+		}
+
+		reqLogger.Update(reqLogger.Entry().WithField("method", handler.Name))
+
+		claims := websocketAuthorizerClaims(event)
+		if s.RequiresAuthentication(handler) && len(claims) == 0 {
+			reqLogger.Entry().WithError(errors.New("wrap websocket api gateway: request has no authorizer context")).Warn("request failed")
+			if isConnect {
+				return s.apiGatewayErrorResponse(ctx, hand.New(runtime.ErrCodeNoAuthentication)), nil
+			}
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+		}
+		if s.UsesIdentity() && len(claims) > 0 {
+			ctx = s.ProvideIdentity(ctx, claims)
+		}
+
+		if err := s.checkTimeRemaining(ctx); err != nil {
+			reqLogger.Entry().WithError(err).Warn("request rejected near deadline")
+			if isConnect {
+				return s.apiGatewayErrorResponse(ctx, err), nil
+			}
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+		}
+
+		ctx, err = s.ProvideContext(ctx)
+		if err != nil {
+			reqLogger.Entry().WithError(err).WithFields(logMetaFields(err)).Log(s.ErrorLogLevel(errorCode(err)), "request failed")
+			if isConnect {
+				return s.apiGatewayErrorResponse(ctx, err), nil
+			}
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+		}
+
+		result, err := s.Invoke(ctx, handler, []byte(event.Body))
+		if err != nil {
+			if isConnect {
+				return s.apiGatewayErrorResponse(ctx, err), nil
+			}
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+		}
+
+		if result == nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+		}
+
+		resBytes, err := json.Marshal(s.EnvelopeResult(ctx, result))
+		if err != nil {
+			reqLogger.Entry().WithError(fmt.Errorf("wrap websocket api gateway: encoding response body failed: %w", err)).Error("request failed")
+			if isConnect {
+				return s.apiGatewayErrorResponse(ctx, err), nil
+			}
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+		}
+
+		return events.APIGatewayProxyResponse{
+			StatusCode:      http.StatusOK,
+			Body:            string(resBytes),
+			IsBase64Encoded: false,
+		}, nil
+	}
+}
+
+// websocketAuthorizerClaims returns the context set by the Lambda authorizer of the $connect route, which API Gateway
+// passes with every event on the connection
+func websocketAuthorizerClaims(event events.APIGatewayWebsocketProxyRequest) map[string]interface{} {
+	claims, _ := event.RequestContext.Authorizer.(map[string]interface{})
+	return claims
+}
@@ -0,0 +1,226 @@
+// Package schema derives JSON Schemas from Go types, so method request schemas cannot drift from request structs
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// FromStruct builds a JSON Schema loader from a struct value or pointer, for use with rpcservice.Service.AddMethod.
+// Property names are taken from json tags. Fields tagged validate:"required" are required,
+// and validate:"oneof=a b c" restricts a field to an enum of space separated values.
+func FromStruct(v interface{}) gojsonschema.JSONLoader {
+	s, err := Generate(reflect.TypeOf(v))
+	if err != nil {
+		panic(fmt.Errorf("runtime cannot generate schema: %w", err))
+	}
+
+	return gojsonschema.NewGoLoader(s)
+}
+
+// Generate builds a JSON Schema document for a Go type
+func Generate(t reflect.Type) (map[string]interface{}, error) {
+	s, err := typeSchema(t)
+	if err != nil {
+		return nil, err
+	}
+
+	s["$schema"] = "http://json-schema.org/draft-07/schema#"
+
+	return s, nil
+}
+
+func typeSchema(t reflect.Type) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+
+	case reflect.Slice, reflect.Array:
+		items, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key must be string, %s provided", t.Key().Kind())
+		}
+		values, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+
+	case reflect.Struct:
+		return structSchema(t)
+
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func structSchema(t reflect.Type) (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	if err := addFields(t, properties, &required); err != nil {
+		return nil, err
+	}
+
+	s := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+
+	return s, nil
+}
+
+func addFields(t reflect.Type, properties map[string]interface{}, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		// embedded structs without a json name are flattened, as encoding/json does
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := addFields(ft, properties, required); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fs, err := typeSchema(field.Type)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			switch {
+			case rule == "required":
+				*required = append(*required, name)
+			case strings.HasPrefix(rule, "oneof="):
+				enum, err := enumValues(field.Type, strings.Fields(strings.TrimPrefix(rule, "oneof=")))
+				if err != nil {
+					return fmt.Errorf("field %s: %w", field.Name, err)
+				}
+				fs["enum"] = enum
+			}
+		}
+
+		properties[name] = fs
+	}
+
+	return nil
+}
+
+func jsonName(field reflect.StructField) (name string, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+
+	name = strings.Split(tag, ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+
+	return name, false
+}
+
+// enumValues converts tag values into JSON values matching the field type
+func enumValues(t reflect.Type, values []string) ([]interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	enum := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if t.Kind() == reflect.String {
+			enum = append(enum, v)
+			continue
+		}
+
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(v), &parsed); err != nil {
+			return nil, fmt.Errorf("invalid enum value %q for %s", v, t)
+		}
+		enum = append(enum, parsed)
+	}
+
+	return enum, nil
+}
+
+// PageOf builds a JSON Schema loader for a runtime.Page response whose items are values of the item type,
+// for documenting list methods
+func PageOf(item interface{}) gojsonschema.JSONLoader {
+	s, err := GeneratePage(reflect.TypeOf(item))
+	if err != nil {
+		panic(fmt.Errorf("runtime cannot generate page schema: %w", err))
+	}
+
+	return gojsonschema.NewGoLoader(s)
+}
+
+// GeneratePage builds a JSON Schema document for a runtime.Page of the item type
+func GeneratePage(item reflect.Type) (map[string]interface{}, error) {
+	itemSchema, err := typeSchema(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"items":       map[string]interface{}{"type": "array", "items": itemSchema},
+			"next_cursor": map[string]interface{}{"type": "string"},
+			"total":       map[string]interface{}{"type": "integer", "minimum": 0},
+		},
+		"required": []string{"items"},
+	}, nil
+}
@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/g-wilson/runtime"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+type pageItem struct {
+	ID   string `json:"id" validate:"required"`
+	Name string `json:"name"`
+}
+
+func TestGeneratePage(t *testing.T) {
+	s, err := GeneratePage(reflect.TypeOf(pageItem{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if required, _ := s["required"].([]string); len(required) != 1 || required[0] != "items" {
+		t.Fatalf("expected items to be required, got %v", s["required"])
+	}
+
+	items := s["properties"].(map[string]interface{})["items"].(map[string]interface{})
+	if items["type"] != "array" {
+		t.Fatalf("expected items to be an array, got %v", items)
+	}
+	if itemSchema := items["items"].(map[string]interface{}); itemSchema["type"] != "object" {
+		t.Fatalf("expected the item schema to describe the struct, got %v", itemSchema)
+	}
+}
+
+func TestGeneratePageUnsupportedItem(t *testing.T) {
+	if _, err := GeneratePage(reflect.TypeOf(make(chan int))); err == nil {
+		t.Fatal("expected an unsupported item type to fail")
+	}
+}
+
+func TestPageOfValidatesPages(t *testing.T) {
+	sc, err := gojsonschema.NewSchema(PageOf(pageItem{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		doc   interface{}
+		valid bool
+	}{
+		{"page", runtime.NewPage([]pageItem{{ID: "a", Name: "first"}}, "a").WithTotal(3), true},
+		{"empty page", runtime.NewPage(nil, ""), true},
+		{"item missing required field", map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "first"}}}, false},
+		{"items not an array", map[string]interface{}{"items": "a"}, false},
+		{"missing items", map[string]interface{}{"next_cursor": "a"}, false},
+		{"negative total", map[string]interface{}{"items": []interface{}{}, "total": -1}, false},
+	}
+
+	for _, tt := range tests {
+		result, err := sc.Validate(gojsonschema.NewGoLoader(tt.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Valid() != tt.valid {
+			t.Errorf("%s: expected valid %t, got errors %v", tt.name, tt.valid, result.Errors())
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package runtime
+
+import (
+	"context"
+
+	"github.com/g-wilson/runtime/internal/ctxkey"
+)
+
+var tenantKey = ctxkey.New("tenant")
+
+// WithTenant adds the tenant of the authenticated caller to a context
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// TenantFromContext retrieves the tenant of the authenticated caller from the context
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey).(string)
+	return tenant, ok && tenant != ""
+}
@@ -0,0 +1,35 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+
+	"github.com/g-wilson/runtime/internal/ctxkey"
+)
+
+var traceIDKey = ctxkey.New("traceid")
+
+// WithTraceID adds a distributed trace ID to a context
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey, id)
+}
+
+// TraceIDFromContext retrieves the distributed trace ID from the context
+func TraceIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(traceIDKey).(string)
+	return id, ok
+}
+
+// ParseXRayTraceID extracts the root trace ID from an AWS X-Ray trace header,
+// such as Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1
+// It returns an empty string if the header has no root.
+func ParseXRayTraceID(header string) string {
+	for _, part := range strings.Split(header, ";") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) == 2 && kv[0] == "Root" {
+			return kv[1]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,18 @@
+package runtime
+
+import "testing"
+
+func TestParseXRayTraceID(t *testing.T) {
+	tests := map[string]string{
+		"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1": "1-5759e988-bd862e3fe1be46a994272793",
+		"Parent=53995c3f42cd8ad8; Root=1-5759e988-bd862e3fe1be46a994272793":          "1-5759e988-bd862e3fe1be46a994272793",
+		"Parent=53995c3f42cd8ad8;Sampled=1":                                          "",
+		"":                                                                           "",
+	}
+
+	for header, expected := range tests {
+		if actual := ParseXRayTraceID(header); actual != expected {
+			t.Errorf("%q: expected %q, got %q", header, expected, actual)
+		}
+	}
+}